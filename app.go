@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// GitHub accepts JWT expires in 10 minutes at most.
+	appJWTExpiration = 9 * time.Minute
+	// issued a little earlier to allow clock drift
+	appJWTClockDrift = time.Minute
+)
+
+// AppOptions authenticates as an installation of GitHub App.
+type AppOptions struct {
+	AppID          int64
+	PrivateKeyFile string
+	InstallationID int64
+}
+
+// newAppTransport returns transport authenticating as the app with JWT
+// signed by its private key.
+func newAppTransport(opt AppOptions, base http.RoundTripper) (*appTransport, error) {
+	b, err := os.ReadFile(opt.PrivateKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile")
+	}
+	key, err := parseRSAPrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+	return &appTransport{
+		appID: opt.AppID,
+		key:   key,
+		base:  base,
+	}, nil
+}
+
+// newAppTokenSource returns token source minting installation token and
+// refreshing it before expiration. appCli should be authenticated as the app.
+func newAppTokenSource(ctx context.Context, appCli *github.Client, installationID int64) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &appTokenSource{
+		ctx:            ctx,
+		cli:            appCli,
+		installationID: installationID,
+	})
+}
+
+type appTokenSource struct {
+	ctx            context.Context
+	cli            *github.Client
+	installationID int64
+}
+
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	t, _, err := s.cli.Apps.CreateInstallationToken(s.ctx, s.installationID, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cli.Apps.CreateInstallationToken")
+	}
+	return &oauth2.Token{
+		AccessToken: t.GetToken(),
+		Expiry:      t.GetExpiresAt(),
+	}, nil
+}
+
+// appTransport authenticates requests as GitHub App with JWT.
+type appTransport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := signAppJWT(t.appID, t.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTripper should not modify the given request
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(r)
+}
+
+// signAppJWT returns JWT signed by private key of the app with RS256.
+func signAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "json.Marshal")
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appJWTClockDrift).Unix(),
+		"exp": now.Add(appJWTExpiration).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "json.Marshal")
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "rsa.SignPKCS1v15")
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// parseRSAPrivateKey parses PEM encoded private key in either PKCS #1 or
+// PKCS #8 which GitHub App generates.
+func parseRSAPrivateKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "x509.ParsePKCS8PrivateKey")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rsaKey, nil
+}
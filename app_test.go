@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGitHubClient_app(t *testing.T) {
+	const (
+		mockOwner          = "some-org"
+		mockAppID          = 1234
+		mockInstallationID = 42
+	)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0o600))
+
+	cases := []struct {
+		name           string
+		expiresIn      time.Duration
+		expectedMinted int
+	}{
+		{
+			name:           "reuse installation token",
+			expiresIn:      time.Hour,
+			expectedMinted: 1,
+		},
+		{
+			name:           "refresh expired installation token",
+			expiresIn:      0,
+			expectedMinted: 2,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			minted := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/api/v3/app/installations/%d/access_tokens", mockInstallationID) {
+					assertAppJWT(t, &key.PublicKey, mockAppID, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+					minted++
+					rw.Header().Set("Content-Type", "application/json")
+					rw.WriteHeader(http.StatusCreated)
+					_, err := io.WriteString(rw, fmt.Sprintf(`{"token": "installation-token-%d", "expires_at": "%s"}`,
+						minted, time.Now().Add(tc.expiresIn).UTC().Format(time.RFC3339)))
+					require.NoError(t, err)
+					return
+				}
+				if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/api/v3/orgs/%s/repos", mockOwner) {
+					assert.Equal(t, fmt.Sprintf("Bearer installation-token-%d", minted), r.Header.Get("Authorization"))
+					rw.Header().Set("Content-Type", "application/json")
+					_, err := io.WriteString(rw, `[]`)
+					require.NoError(t, err)
+					return
+				}
+				t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			}))
+			defer server.Close()
+
+			cli, err := NewGitHubClient(context.Background(), ClientOptions{
+				BaseURL: server.URL,
+				App: &AppOptions{
+					AppID:          mockAppID,
+					PrivateKeyFile: keyFile,
+					InstallationID: mockInstallationID,
+				},
+			})
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				_, err := ListActivatedRepositories(context.Background(), cli, mockOwner, nil)
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedMinted, minted)
+		})
+	}
+}
+
+func assertAppJWT(t *testing.T, pub *rsa.PublicKey, appID int64, token string) {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig))
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	require.NoError(t, json.Unmarshal(b, &claims))
+	assert.Equal(t, fmt.Sprint(appID), claims.Issuer)
+	assert.True(t, claims.IssuedAt < time.Now().Unix())
+	assert.True(t, claims.ExpiresAt > time.Now().Unix())
+	assert.True(t, claims.ExpiresAt-claims.IssuedAt <= int64((10*time.Minute).Seconds()))
+}
+
+func Test_parseRSAPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		given    []byte
+		expected bool
+	}{
+		{
+			name:     "PKCS #1",
+			given:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+			expected: true,
+		},
+		{
+			name:     "PKCS #8",
+			given:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			expected: true,
+		},
+		{
+			name:     "not PEM",
+			given:    []byte("invalid"),
+			expected: false,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseRSAPrivateKey(tc.given)
+
+			if !tc.expected {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, key.D, got.D)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"strings"
+)
+
+const (
+	whitespaces = " \t"
+)
+
+// File is a parsed CODEOWNERS file. It keeps every byte of the original
+// content so that String returns exactly what has been parsed unless it is
+// modified.
+type File struct {
+	Lines []*Line
+}
+
+// Line is a single line of CODEOWNERS file. A line is either blank, a comment
+// or a rule which may have an inline comment.
+type Line struct {
+	// Number is 1-based line number.
+	Number int
+	// Indent is leading whitespace. Whole whitespace of blank line goes here.
+	Indent string
+	Rule   *Rule
+	// Comment starts with "#". It's inline comment if Rule is not nil.
+	Comment string
+	// EOL is line ending, which is one of "\n", "\r\n" or empty for the last line.
+	EOL string
+}
+
+// Rule is a pattern followed by its owners.
+type Rule struct {
+	// Line is 1-based line number.
+	Line int
+	// Pattern is written as is, including escape characters.
+	Pattern string
+	// Space is whitespace following the pattern.
+	Space  string
+	Owners []*Owner
+}
+
+// OwnerKind is a kind of owner written in CODEOWNERS file.
+type OwnerKind int
+
+const (
+	UnknownOwner OwnerKind = iota
+	// UserOwner is written as "@user".
+	UserOwner
+	// TeamOwner is written as "@org/team".
+	TeamOwner
+	// EmailOwner is written as "user@example.com".
+	EmailOwner
+)
+
+func (k OwnerKind) String() string {
+	switch k {
+	case UserOwner:
+		return "user"
+	case TeamOwner:
+		return "team"
+	case EmailOwner:
+		return "email"
+	}
+	return "unknown"
+}
+
+// Owner is an owner token of the rule.
+type Owner struct {
+	// Token is written as is, e.g. "@org/team".
+	Token string
+	// Space is whitespace following the token.
+	Space string
+}
+
+// Parse parses content of CODEOWNERS file.
+func Parse(s string) *File {
+	if s == "" {
+		return &File{}
+	}
+
+	ss := strings.Split(s, sep)
+	// trailing newline doesn't make a new line
+	if ss[len(ss)-1] == "" {
+		ss = ss[:len(ss)-1]
+	}
+
+	f := &File{
+		Lines: make([]*Line, len(ss)),
+	}
+	for i, l := range ss {
+		eol := sep
+		if i == len(ss)-1 && !strings.HasSuffix(s, sep) {
+			eol = ""
+		}
+		if strings.HasSuffix(l, "\r") {
+			l = strings.TrimSuffix(l, "\r")
+			eol = "\r" + eol
+		}
+		f.Lines[i] = parseLine(i+1, l, eol)
+	}
+	return f
+}
+
+func parseLine(n int, s, eol string) *Line {
+	l := &Line{
+		Number: n,
+		EOL:    eol,
+	}
+
+	rest := strings.TrimLeft(s, whitespaces)
+	l.Indent = s[:len(s)-len(rest)]
+	if rest == "" {
+		return l
+	}
+	if strings.HasPrefix(rest, commentPrefix) {
+		l.Comment = rest
+		return l
+	}
+
+	r := &Rule{
+		Line: n,
+	}
+	r.Pattern, r.Space, rest = nextToken(rest)
+	for rest != "" {
+		if strings.HasPrefix(rest, commentPrefix) {
+			l.Comment = rest
+			break
+		}
+		o := &Owner{}
+		o.Token, o.Space, rest = nextToken(rest)
+		r.Owners = append(r.Owners, o)
+	}
+	l.Rule = r
+	return l
+}
+
+// nextToken splits s into a token, following whitespace and the rest.
+// Whitespace escaped by backslash is a part of the token.
+func nextToken(s string) (token, space, rest string) {
+	i := 0
+	for i < len(s) && !isWhitespace(s[i]) {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		i++
+	}
+	j := i
+	for j < len(s) && isWhitespace(s[j]) {
+		j++
+	}
+	return s[:i], s[i:j], s[j:]
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// String returns content of CODEOWNERS file.
+func (f *File) String() string {
+	var b strings.Builder
+	for _, l := range f.Lines {
+		b.WriteString(l.String())
+	}
+	return b.String()
+}
+
+// Rules returns all rules in order.
+func (f *File) Rules() []*Rule {
+	rr := make([]*Rule, 0, len(f.Lines))
+	for _, l := range f.Lines {
+		if l.Rule != nil {
+			rr = append(rr, l.Rule)
+		}
+	}
+	return rr
+}
+
+// OwnerNames returns unique names of owners in order of appearance.
+func (f *File) OwnerNames() []string {
+	nn := make([]string, 0)
+	for _, r := range f.Rules() {
+		for _, o := range r.Owners {
+			if o.Kind() == UnknownOwner {
+				continue
+			}
+			nn = append(nn, o.Name())
+		}
+	}
+	return set(nn)
+}
+
+// String returns the line including its line ending.
+func (l *Line) String() string {
+	var b strings.Builder
+	b.WriteString(l.Indent)
+	if l.Rule != nil {
+		b.WriteString(l.Rule.String())
+	}
+	b.WriteString(l.Comment)
+	b.WriteString(l.EOL)
+	return b.String()
+}
+
+// IsBlank reports whether the line has only whitespace.
+func (l *Line) IsBlank() bool {
+	return l.Rule == nil && l.Comment == ""
+}
+
+// IsComment reports whether the whole line is a comment.
+func (l *Line) IsComment() bool {
+	return l.Rule == nil && l.Comment != ""
+}
+
+// trimTrailingSpace removes whitespace at the end of the rule, but keeps one
+// before inline comment.
+func (l *Line) trimTrailingSpace() {
+	if l.Rule == nil {
+		return
+	}
+	space := &l.Rule.Space
+	if n := len(l.Rule.Owners); n > 0 {
+		space = &l.Rule.Owners[n-1].Space
+	}
+	if l.Comment == "" {
+		*space = ""
+		return
+	}
+	if *space == "" {
+		*space = " "
+	}
+}
+
+// String returns the rule without inline comment.
+func (r *Rule) String() string {
+	var b strings.Builder
+	b.WriteString(r.Pattern)
+	b.WriteString(r.Space)
+	for _, o := range r.Owners {
+		b.WriteString(o.Token)
+		b.WriteString(o.Space)
+	}
+	return b.String()
+}
+
+// Path returns the pattern with escape characters removed.
+func (r *Rule) Path() string {
+	if !strings.Contains(r.Pattern, `\`) {
+		return r.Pattern
+	}
+	var b strings.Builder
+	for i := 0; i < len(r.Pattern); i++ {
+		if r.Pattern[i] == '\\' && i+1 < len(r.Pattern) {
+			i++
+		}
+		b.WriteByte(r.Pattern[i])
+	}
+	return b.String()
+}
+
+// Name returns identifier of the owner, e.g. "org/team" for "@org/team" and
+// "user@example.com" as is.
+func (o *Owner) Name() string {
+	return strings.TrimPrefix(o.Token, mentionPrefix)
+}
+
+// Kind returns kind of the owner.
+func (o *Owner) Kind() OwnerKind {
+	if !strings.HasPrefix(o.Token, mentionPrefix) {
+		if strings.Contains(o.Token, mentionPrefix) {
+			return EmailOwner
+		}
+		return UnknownOwner
+	}
+	return ownerKind(o.Name())
+}
+
+// ownerKind returns kind of owner identified by name, e.g. "user", "org/team"
+// or "user@example.com".
+func ownerKind(name string) OwnerKind {
+	switch {
+	case name == "":
+		return UnknownOwner
+	case strings.Contains(name, mentionPrefix):
+		return EmailOwner
+	case strings.Contains(name, "/"):
+		return TeamOwner
+	}
+	return UserOwner
+}
+
+// newOwner returns owner identified by name. Email is written as is and the
+// others are mentioned.
+func newOwner(name, space string) *Owner {
+	token := mentionPrefix + name
+	if ownerKind(name) == EmailOwner {
+		token = name
+	}
+	return &Owner{
+		Token: token,
+		Space: space,
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name     string
+		given    string
+		expected *File
+	}{
+		{
+			name:     "empty string",
+			given:    "",
+			expected: &File{},
+		},
+		{
+			name:  "rule",
+			given: "* @a\t@org/team\n",
+			expected: &File{
+				Lines: []*Line{
+					{
+						Number: 1,
+						Rule: &Rule{
+							Line:    1,
+							Pattern: "*",
+							Space:   " ",
+							Owners: []*Owner{
+								{Token: "@a", Space: "\t"},
+								{Token: "@org/team"},
+							},
+						},
+						EOL: "\n",
+					},
+				},
+			},
+		},
+		{
+			name:  "comment and blank lines",
+			given: "# comment\n  \n",
+			expected: &File{
+				Lines: []*Line{
+					{Number: 1, Comment: "# comment", EOL: "\n"},
+					{Number: 2, Indent: "  ", EOL: "\n"},
+				},
+			},
+		},
+		{
+			name:  "inline comment",
+			given: "  /docs/ @a # docs team",
+			expected: &File{
+				Lines: []*Line{
+					{
+						Number: 1,
+						Indent: "  ",
+						Rule: &Rule{
+							Line:    1,
+							Pattern: "/docs/",
+							Space:   " ",
+							Owners: []*Owner{
+								{Token: "@a", Space: " "},
+							},
+						},
+						Comment: "# docs team",
+					},
+				},
+			},
+		},
+		{
+			name:  "escaped space and hash in pattern",
+			given: "/example\\ path/a#b @a\r\n\\#c\r\n",
+			expected: &File{
+				Lines: []*Line{
+					{
+						Number: 1,
+						Rule: &Rule{
+							Line:    1,
+							Pattern: "/example\\ path/a#b",
+							Space:   " ",
+							Owners: []*Owner{
+								{Token: "@a"},
+							},
+						},
+						EOL: "\r\n",
+					},
+					{
+						Number: 2,
+						Rule: &Rule{
+							Line:    2,
+							Pattern: "\\#c",
+						},
+						EOL: "\r\n",
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := Parse(tc.given)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestFile_String(t *testing.T) {
+	cases := []struct {
+		name  string
+		given string
+	}{
+		{name: "empty string", given: ""},
+		{name: "newline only", given: "\n\n"},
+		{name: "no trailing newline", given: "* @a\n/docs @b"},
+		{name: "crlf", given: "* @a\r\n\r\n# c\r\n"},
+		{name: "whitespaces", given: "\t* \t@a  @b\t \n   \n"},
+		{name: "inline comment", given: "* @a #c\n/x @b\t# d  \n"},
+		{name: "escaped", given: "/a\\ b/\\#c @a\n"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := Parse(tc.given).String()
+
+			assert.Equal(t, tc.given, got)
+		})
+	}
+}
+
+func TestRule_Path(t *testing.T) {
+	cases := []struct {
+		name     string
+		given    string
+		expected string
+	}{
+		{name: "plain", given: "/docs/*.md", expected: "/docs/*.md"},
+		{name: "escaped space", given: "/a\\ b/", expected: "/a b/"},
+		{name: "escaped hash", given: "\\#c", expected: "#c"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Rule{Pattern: tc.given}
+
+			assert.Equal(t, tc.expected, r.Path())
+		})
+	}
+}
+
+func TestOwner_Kind(t *testing.T) {
+	cases := []struct {
+		name     string
+		given    string
+		expected OwnerKind
+	}{
+		{name: "user", given: "@a", expected: UserOwner},
+		{name: "team", given: "@org/team", expected: TeamOwner},
+		{name: "email", given: "user@example.com", expected: EmailOwner},
+		{name: "unknown", given: "a", expected: UnknownOwner},
+		{name: "empty mention", given: "@", expected: UnknownOwner},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Owner{Token: tc.given}
+
+			assert.Equal(t, tc.expected, o.Kind())
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileName = ".codeowners-tool.yaml"
+	configEnv      = "CODEOWNERS_TOOL_CONFIG"
+)
+
+// Config is defaults of every command kept in configuration file. Flags and
+// environment variables take precedence over it.
+type Config struct {
+	// Org is comma separated owners used when no owner argument is given.
+	Org         string            `yaml:"org"`
+	Host        string            `yaml:"host"`
+	Concurrency int               `yaml:"concurrency"`
+	Repos       RepoConfig        `yaml:"repos"`
+	Branch      string            `yaml:"branch"`
+	PullRequest PullRequestConfig `yaml:"pull_request"`
+	Ignore      IgnoreConfig      `yaml:"ignore"`
+
+	// path is where the configuration is loaded from.
+	path string
+}
+
+// RepoConfig is defaults of flags selecting repositories.
+type RepoConfig struct {
+	Visibility    string   `yaml:"visibility"`
+	Include       []string `yaml:"include"`
+	Exclude       []string `yaml:"exclude"`
+	IncludeRegexp []string `yaml:"include_regexp"`
+	ExcludeRegexp []string `yaml:"exclude_regexp"`
+	Topics        []string `yaml:"topics"`
+	Languages     []string `yaml:"languages"`
+	Forks         string   `yaml:"forks"`
+	Templates     string   `yaml:"templates"`
+	PushedAfter   string   `yaml:"pushed_after"`
+	// File is relative to the configuration file.
+	File string `yaml:"file"`
+}
+
+// PullRequestConfig is defaults of pull requests opened by replace. Commit
+// message, title and body are Go templates.
+type PullRequestConfig struct {
+	CommitMessage string   `yaml:"commit_message"`
+	Title         string   `yaml:"title"`
+	Body          *string  `yaml:"body"`
+	Reviewers     []string `yaml:"reviewers"`
+	Labels        []string `yaml:"labels"`
+}
+
+// IgnoreConfig lists what every command should skip.
+type IgnoreConfig struct {
+	// Owners are never reported by inspect.
+	Owners []string `yaml:"owners"`
+	// Repos are excluded in addition to repos.exclude.
+	Repos []string `yaml:"repos"`
+}
+
+// configFlagEnvs are environment variables taking precedence over
+// configuration for flags.
+var configFlagEnvs = map[string][]string{
+	"github-url": {"GITHUB_API_URL"},
+}
+
+// configFlagAliases are flags standing for others. Giving an alias counts as
+// giving the flag.
+var configFlagAliases = map[string]string{
+	"allow": "include",
+	"deny":  "exclude",
+}
+
+// findConfig returns path of the configuration file in dir or its nearest
+// parent. It returns empty string if there is none.
+func findConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "filepath.Abs")
+	}
+	for {
+		p := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !os.IsNotExist(err) {
+			return "", errors.Wrap(err, "os.Stat")
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig reads and validates the configuration file. Unknown keys are
+// errors to catch typos.
+func LoadConfig(name string) (*Config, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.Open")
+	}
+	defer f.Close()
+
+	c := &Config{path: name}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	// empty file is valid
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrap(err, name)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	return c, nil
+}
+
+// Validate checks every value and reports the first invalid one with its key.
+func (c *Config) Validate() error {
+	invalid := func(key, format string, a ...interface{}) error {
+		return errors.Errorf("%s: %s", key, fmt.Sprintf(format, a...))
+	}
+	if c.Org != "" && len(splitOwners(c.Org)) == 0 {
+		return invalid("org", "no owner in %q", c.Org)
+	}
+	if c.Host != "" {
+		u, err := url.Parse(c.Host)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return invalid("host", "%q should be an absolute URL, e.g. https://github.example.com/api/v3", c.Host)
+		}
+	}
+	if c.Concurrency < 0 {
+		return invalid("concurrency", "should be positive, got %d", c.Concurrency)
+	}
+
+	r := c.Repos
+	if r.Visibility != "" && !contains(visibilities, r.Visibility) {
+		return invalid("repos.visibility", "%q should be one of %s", r.Visibility, strings.Join(visibilities, ", "))
+	}
+	if r.Forks != "" && !contains(selectModes, r.Forks) {
+		return invalid("repos.forks", "%q should be one of %s", r.Forks, strings.Join(selectModes, ", "))
+	}
+	if r.Templates != "" && !contains(selectModes, r.Templates) {
+		return invalid("repos.templates", "%q should be one of %s", r.Templates, strings.Join(selectModes, ", "))
+	}
+	globs := []struct {
+		key      string
+		patterns []string
+	}{
+		{"repos.include", r.Include},
+		{"repos.exclude", r.Exclude},
+		{"ignore.repos", c.Ignore.Repos},
+	}
+	for _, g := range globs {
+		for _, p := range g.patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return invalid(g.key, "invalid glob %q", p)
+			}
+		}
+	}
+	regexps := []struct {
+		key      string
+		patterns []string
+	}{
+		{"repos.include_regexp", r.IncludeRegexp},
+		{"repos.exclude_regexp", r.ExcludeRegexp},
+	}
+	for _, re := range regexps {
+		for _, p := range re.patterns {
+			if _, err := regexp.Compile(p); err != nil {
+				return invalid(re.key, "invalid regexp %q: %v", p, err)
+			}
+		}
+	}
+	if r.PushedAfter != "" {
+		if _, err := time.Parse("2006-01-02", r.PushedAfter); err != nil {
+			return invalid("repos.pushed_after", "%q should be a date as YYYY-MM-DD", r.PushedAfter)
+		}
+	}
+
+	if c.Branch != "" && !isValidBranch(c.Branch) {
+		return invalid("branch", "%q is not a valid branch name", c.Branch)
+	}
+	pr := c.PullRequest
+	if _, err := newReplaceTemplates(pr.CommitMessage, pr.Title, pr.Body); err != nil {
+		return invalid("pull_request", "%v", err)
+	}
+	for _, r := range c.PullRequest.Reviewers {
+		if ownerKind(trimMention(r)) != UserOwner && ownerKind(trimMention(r)) != TeamOwner {
+			return invalid("pull_request.reviewers", "%q should be a user or org/team", r)
+		}
+	}
+	for _, l := range c.PullRequest.Labels {
+		if strings.TrimSpace(l) == "" {
+			return invalid("pull_request.labels", "label should not be empty")
+		}
+	}
+	for _, o := range c.Ignore.Owners {
+		if ownerKind(trimMention(o)) == UnknownOwner {
+			return invalid("ignore.owners", "%q is not a user, org/team or email", o)
+		}
+	}
+	return nil
+}
+
+// isValidBranch roughly checks the name by rules of git check-ref-format.
+func isValidBranch(name string) bool {
+	if strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".lock") ||
+		strings.HasPrefix(name, "-") || strings.Contains(name, "..") || strings.Contains(name, "@{") || strings.Contains(name, "//") {
+		return false
+	}
+	return !strings.ContainsAny(name, " ~^:?*[\\\t\n")
+}
+
+// flagValues returns values of flags in configuration. Values of repeatable
+// flags are set one by one. ignore.repos is not included since it's merged
+// even if the flag is given.
+func (c *Config) flagValues() [][2]string {
+	var vv [][2]string
+	add := func(name string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				vv = append(vv, [2]string{name, v})
+			}
+		}
+	}
+	add("github-url", c.Host)
+	if c.Concurrency > 0 {
+		add("concurrency", strconv.Itoa(c.Concurrency))
+	}
+	add("visibility", c.Repos.Visibility)
+	add("include", c.Repos.Include...)
+	add("exclude", c.Repos.Exclude...)
+	add("include-regexp", c.Repos.IncludeRegexp...)
+	add("exclude-regexp", c.Repos.ExcludeRegexp...)
+	add("topic", c.Repos.Topics...)
+	add("language", c.Repos.Languages...)
+	add("forks", c.Repos.Forks)
+	add("templates", c.Repos.Templates)
+	add("pushed-after", c.Repos.PushedAfter)
+	if c.Repos.File != "" {
+		p := c.Repos.File
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(c.path), p)
+		}
+		add("repos-file", p)
+	}
+	add("branch", c.Branch)
+	add("commit-message", c.PullRequest.CommitMessage)
+	add("pr-title", c.PullRequest.Title)
+	if c.PullRequest.Body != nil {
+		vv = append(vv, [2]string{"pr-body", *c.PullRequest.Body})
+	}
+	add("reviewer", c.PullRequest.Reviewers...)
+	add("label", c.PullRequest.Labels...)
+	add("ignore-owner", c.Ignore.Owners...)
+	return vv
+}
+
+// apply sets flags of the flag set by configuration unless they are given
+// explicitly, by their aliases or by environment variables. Flags the command
+// doesn't have are ignored. ignore.repos is always added to --exclude.
+func (c *Config) apply(fs *flag.FlagSet) error {
+	given := make(map[string]struct{})
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = struct{}{}
+		if name, ok := configFlagAliases[f.Name]; ok {
+			given[name] = struct{}{}
+		}
+	})
+	for name, envs := range configFlagEnvs {
+		for _, e := range envs {
+			if os.Getenv(e) != "" {
+				given[name] = struct{}{}
+			}
+		}
+	}
+
+	for _, v := range c.flagValues() {
+		name, value := v[0], v[1]
+		if _, ok := given[name]; ok || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return errors.Wrapf(err, "%s: --%s", c.path, name)
+		}
+	}
+	if fs.Lookup("exclude") == nil {
+		return nil
+	}
+	for _, r := range c.Ignore.Repos {
+		if err := fs.Set("exclude", r); err != nil {
+			return errors.Wrapf(err, "%s: --exclude", c.path)
+		}
+	}
+	return nil
+}
+
+// owners returns owners in configuration if args is empty.
+func (c *Config) owners(args []string) []string {
+	if len(args) > 0 || c == nil || c.Org == "" {
+		return args
+	}
+	return []string{c.Org}
+}
+
+// parseCommand parses flags and positional arguments like parseArgs, and
+// applies configuration of --config flag, $CODEOWNERS_TOOL_CONFIG or the file
+// found in the working directory or its parents. It returns nil
+// configuration if there is none.
+func parseCommand(fs *flag.FlagSet, args []string) ([]string, *Config, error) {
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var name string
+	if f := fs.Lookup("config"); f != nil {
+		name = f.Value.String()
+	}
+	if name == "" {
+		name = os.Getenv(configEnv)
+	}
+	if name == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "os.Getwd")
+		}
+		name, err = findConfig(wd)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if name == "" {
+		return args, nil, nil
+	}
+
+	c, err := LoadConfig(name)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid config")
+	}
+	if err := c.apply(fs); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid config")
+	}
+	return args, c, nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	name := filepath.Join(dir, configFileName)
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	return name
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		name := writeConfig(t, t.TempDir(), `
+org: org
+host: https://github.example.com/api/v3
+concurrency: 8
+repos:
+  visibility: all
+  include: [api-*]
+  exclude_regexp: ['-test$']
+  forks: exclude
+  pushed_after: 2022-01-01
+branch: chore/codeowners
+pull_request:
+  title: Update codeowners
+  body: ""
+  reviewers: [a, org/team]
+  labels: [codeowners]
+ignore:
+  owners: [bot]
+  repos: [legacy]
+`)
+
+		got, err := LoadConfig(name)
+
+		require.NoError(t, err)
+		assert.Equal(t, "org", got.Org)
+		assert.Equal(t, 8, got.Concurrency)
+		assert.Equal(t, []string{"api-*"}, got.Repos.Include)
+		assert.Equal(t, "chore/codeowners", got.Branch)
+		require.NotNil(t, got.PullRequest.Body)
+		assert.Equal(t, "", *got.PullRequest.Body)
+		assert.Equal(t, []string{"legacy"}, got.Ignore.Repos)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		name := writeConfig(t, t.TempDir(), "")
+
+		got, err := LoadConfig(name)
+
+		require.NoError(t, err)
+		assert.Equal(t, "", got.Org)
+	})
+
+	cases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "unknown key",
+			content:  "repos:\n  visbility: all\n",
+			expected: "field visbility not found",
+		},
+		{
+			name:     "invalid type",
+			content:  "concurrency: many\n",
+			expected: "line 1",
+		},
+		{
+			name:     "invalid host",
+			content:  "host: github.example.com\n",
+			expected: "host: \"github.example.com\" should be an absolute URL",
+		},
+		{
+			name:     "invalid visibility",
+			content:  "repos:\n  visibility: secret\n",
+			expected: "repos.visibility: \"secret\" should be one of public, private, internal, all",
+		},
+		{
+			name:     "invalid regexp",
+			content:  "repos:\n  include_regexp: ['(']\n",
+			expected: "repos.include_regexp: invalid regexp",
+		},
+		{
+			name:     "invalid date",
+			content:  "repos:\n  pushed_after: yesterday\n",
+			expected: "repos.pushed_after",
+		},
+		{
+			name:     "invalid branch",
+			content:  "branch: update codeowners\n",
+			expected: "branch: \"update codeowners\" is not a valid branch name",
+		},
+		{
+			name:     "invalid reviewer",
+			content:  "pull_request:\n  reviewers: [a@example.com]\n",
+			expected: "pull_request.reviewers",
+		},
+		{
+			name:     "invalid template",
+			content:  "pull_request:\n  title: '{{.Title}}'\n",
+			expected: "pull_request: invalid template of pull request title",
+		},
+		{
+			name:     "invalid ignored owner",
+			content:  "ignore:\n  owners: ['@']\n",
+			expected: "ignore.owners",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			name := writeConfig(t, t.TempDir(), tc.content)
+
+			_, err := LoadConfig(name)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func Test_findConfig(t *testing.T) {
+	root := t.TempDir()
+	name := writeConfig(t, root, "org: org\n")
+	dir := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+
+	got, err := findConfig(dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, name, got)
+}
+
+func TestConfig_apply(t *testing.T) {
+	newFlagSet := func() (*flag.FlagSet, *githubFlags, *poolFlags, *selectorFlags) {
+		var (
+			gh   githubFlags
+			pool poolFlags
+			sf   selectorFlags
+		)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		gh.register(fs)
+		pool.register(fs)
+		sf.register(fs)
+		return fs, &gh, &pool, &sf
+	}
+	c := &Config{
+		path:        "/etc/codeowners/" + configFileName,
+		Host:        "https://github.example.com/api/v3",
+		Concurrency: 8,
+		Repos: RepoConfig{
+			Visibility: visibilityAll,
+			Exclude:    []string{"sandbox-*"},
+			File:       "repos.txt",
+		},
+		Ignore: IgnoreConfig{
+			Repos: []string{"legacy"},
+		},
+		Branch: "chore/codeowners",
+	}
+
+	t.Run("config", func(t *testing.T) {
+		t.Setenv("GITHUB_API_URL", "")
+		fs, gh, pool, sf := newFlagSet()
+		require.NoError(t, fs.Parse(nil))
+
+		err := c.apply(fs)
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://github.example.com/api/v3", gh.url)
+		assert.Equal(t, 8, pool.concurrency)
+		assert.Equal(t, visibilityAll, sf.visibility)
+		assert.Equal(t, []string{"sandbox-*", "legacy"}, []string(sf.exclude))
+		assert.Equal(t, "/etc/codeowners/repos.txt", sf.reposFile)
+	})
+
+	t.Run("flags and env take precedence", func(t *testing.T) {
+		t.Setenv("GITHUB_API_URL", "https://env.example.com/api/v3")
+		fs, gh, pool, sf := newFlagSet()
+		require.NoError(t, fs.Parse([]string{"--concurrency", "2", "--exclude", "old"}))
+
+		err := c.apply(fs)
+
+		require.NoError(t, err)
+		assert.Equal(t, "", gh.url)
+		assert.Equal(t, 2, pool.concurrency)
+		assert.Equal(t, visibilityAll, sf.visibility)
+		assert.Equal(t, []string{"old", "legacy"}, []string(sf.exclude))
+	})
+
+	t.Run("aliases", func(t *testing.T) {
+		fs, _, _, sf := newFlagSet()
+		fs.Var(&sf.include, "allow", "")
+		fs.Var(&sf.exclude, "deny", "")
+		require.NoError(t, fs.Parse([]string{"--allow", "web", "--deny", "old"}))
+		c := &Config{
+			Repos:  RepoConfig{Include: []string{"api-*"}, Exclude: []string{"sandbox-*"}},
+			Ignore: IgnoreConfig{Repos: []string{"legacy"}},
+		}
+
+		err := c.apply(fs)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"web"}, []string(sf.include))
+		assert.Equal(t, []string{"old", "legacy"}, []string(sf.exclude))
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		fs, _, _, _ := newFlagSet()
+		var prBody string
+		fs.StringVar(&prBody, "pr-body", defaultPRBodyTemplate, "")
+		require.NoError(t, fs.Parse(nil))
+		empty := ""
+		c := &Config{PullRequest: PullRequestConfig{Body: &empty}}
+
+		err := c.apply(fs)
+
+		require.NoError(t, err)
+		assert.Equal(t, "", prBody)
+	})
+}
+
+func Test_parseCommand(t *testing.T) {
+	name := writeConfig(t, t.TempDir(), "org: org1,org2\nconcurrency: 3\n")
+
+	t.Run("config flag", func(t *testing.T) {
+		var pool poolFlags
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("config", "", "")
+		pool.register(fs)
+
+		args, cfg, err := parseCommand(fs, []string{"--config", name})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"org1,org2"}, cfg.owners(args))
+		assert.Equal(t, 3, pool.concurrency)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(configEnv, name)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("config", "", "")
+
+		args, cfg, err := parseCommand(fs, []string{"org3"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"org3"}, cfg.owners(args))
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/pkg/errors"
+)
+
+// rootDir is the path of repository root in coverage report.
+const rootDir = "/"
+
+// Coverage is how many files and directories of a repository have owners.
+type Coverage struct {
+	Files      int
+	OwnedFiles int
+	Dirs       int
+	OwnedDirs  int
+	// Unowned are subtrees nobody owns any file of, in descending order of
+	// the number of files. File not in such directory is a subtree itself.
+	Unowned []*UnownedTree
+}
+
+// UnownedTree is a directory or a file nobody owns.
+type UnownedTree struct {
+	// Path of directory ends with "/".
+	Path  string
+	Files int
+}
+
+// FilePercent returns percentage of owned files. It's 100 if there is no file.
+func (c *Coverage) FilePercent() float64 {
+	return percent(c.OwnedFiles, c.Files)
+}
+
+// DirPercent returns percentage of owned directories except the root. It's 100
+// if there is no directory.
+func (c *Coverage) DirPercent() float64 {
+	return percent(c.OwnedDirs, c.Dirs)
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// GetRepoCoverage returns coverage of the effective CODEOWNERS file over the
+// repository tree at ref. The default branch is used if ref is nil.
+// Repository without CODEOWNERS file has no owner at all.
+func GetRepoCoverage(ctx context.Context, cli *github.Client, r *github.Repository, ref *string) (*Coverage, error) {
+	f := &File{}
+	content, err := GetCodeownersContentAt(ctx, cli, r, ref)
+	switch {
+	case errors.Cause(err) == ErrNotFound:
+	case err != nil:
+		return nil, err
+	default:
+		s, err := content.GetContent()
+		if err != nil {
+			return nil, errors.Wrap(err, "content.GetContent")
+		}
+		f = Parse(s)
+	}
+
+	files, err := ListFiles(ctx, cli, r, ref)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeCoverage(f, files)
+}
+
+// ComputeCoverage matches every file and its parent directories against the
+// file. Path is owned if the last matching rule has any owner.
+func ComputeCoverage(f *File, files []string) (*Coverage, error) {
+	m, err := NewMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coverage{Files: len(files)}
+	unownedFiles := make(map[string]struct{})
+	dirFiles := make(map[string]int)
+	dirUnowned := make(map[string]int)
+	for _, p := range files {
+		owned := isOwned(m.Match(p))
+		if owned {
+			c.OwnedFiles++
+		} else {
+			unownedFiles[p] = struct{}{}
+		}
+		for _, d := range parentDirs(p) {
+			dirFiles[d]++
+			if !owned {
+				dirUnowned[d]++
+			}
+		}
+	}
+
+	fullyUnowned := func(d string) bool {
+		return dirFiles[d] > 0 && dirUnowned[d] == dirFiles[d]
+	}
+	for d := range dirFiles {
+		if d == rootDir {
+			continue
+		}
+		c.Dirs++
+		if isOwned(m.Match(d)) {
+			c.OwnedDirs++
+		}
+		if fullyUnowned(d) && !fullyUnowned(parentDir(d)) {
+			c.Unowned = append(c.Unowned, &UnownedTree{Path: d, Files: dirFiles[d]})
+		}
+	}
+	if fullyUnowned(rootDir) {
+		c.Unowned = []*UnownedTree{{Path: rootDir, Files: dirFiles[rootDir]}}
+	} else {
+		for p := range unownedFiles {
+			if !fullyUnowned(parentDir(p)) {
+				c.Unowned = append(c.Unowned, &UnownedTree{Path: p, Files: 1})
+			}
+		}
+	}
+	sort.Slice(c.Unowned, func(i, j int) bool {
+		if c.Unowned[i].Files != c.Unowned[j].Files {
+			return c.Unowned[i].Files > c.Unowned[j].Files
+		}
+		return c.Unowned[i].Path < c.Unowned[j].Path
+	})
+	return c, nil
+}
+
+// isOwned reports whether the rule has any valid owner.
+func isOwned(r *Rule) bool {
+	if r == nil {
+		return false
+	}
+	for _, o := range r.Owners {
+		if o.Kind() != UnknownOwner {
+			return true
+		}
+	}
+	return false
+}
+
+// parentDirs returns every ancestor directory of the path from the root.
+func parentDirs(p string) []string {
+	dd := []string{rootDir}
+	parts := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		dd = append(dd, strings.Join(parts[:i], "/")+"/")
+	}
+	return dd
+}
+
+// parentDir returns the directory containing the path.
+func parentDir(p string) string {
+	d := path.Dir(strings.TrimSuffix(p, "/"))
+	if d == "." {
+		return rootDir
+	}
+	return d + "/"
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	files := []string{
+		"README.md",
+		"main.go",
+		"docs/a.md",
+		"docs/b.md",
+		"vendor/x/a.go",
+		"vendor/x/b.go",
+		"vendor/y/c.go",
+		"scripts/run.sh",
+		"scripts/lint.go",
+	}
+
+	cases := []struct {
+		name     string
+		given    string
+		expected *Coverage
+	}{
+		{
+			name:  "partially owned",
+			given: "*.go @a\n/docs/ @org/docs\n/vendor/\n",
+			expected: &Coverage{
+				Files:      9,
+				OwnedFiles: 4,
+				Dirs:       5,
+				OwnedDirs:  1,
+				Unowned: []*UnownedTree{
+					{Path: "vendor/", Files: 3},
+					{Path: "README.md", Files: 1},
+					{Path: "scripts/run.sh", Files: 1},
+				},
+			},
+		},
+		{
+			name:  "fully owned",
+			given: "* @a\n",
+			expected: &Coverage{
+				Files:      9,
+				OwnedFiles: 9,
+				Dirs:       5,
+				OwnedDirs:  5,
+			},
+		},
+		{
+			name:  "no owner",
+			given: "# nobody\n* invalid\n",
+			expected: &Coverage{
+				Files: 9,
+				Dirs:  5,
+				Unowned: []*UnownedTree{
+					{Path: "/", Files: 9},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ComputeCoverage(Parse(tc.given), files)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestCoverage_FilePercent(t *testing.T) {
+	assert.Equal(t, 100.0, (&Coverage{}).FilePercent())
+	assert.Equal(t, 25.0, (&Coverage{Files: 4, OwnedFiles: 1}).FilePercent())
+	assert.Equal(t, 50.0, (&Coverage{Dirs: 2, OwnedDirs: 1}).DirPercent())
+}
+
+func Test_parentDirs(t *testing.T) {
+	assert.Equal(t, []string{"/"}, parentDirs("a"))
+	assert.Equal(t, []string{"/", "a/", "a/b/"}, parentDirs("a/b/c"))
+	assert.Equal(t, "/", parentDir("a"))
+	assert.Equal(t, "/", parentDir("a/"))
+	assert.Equal(t, "a/b/", parentDir("a/b/c"))
+}
+
+func TestGetRepoCoverage(t *testing.T) {
+	const (
+		mockOwner = "some-org"
+		mockRepo  = "some-repo"
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/api/v3/repos/%s/%s/contents/.github/CODEOWNERS", mockOwner, mockRepo) {
+			assert.Equal(t, "release", r.URL.Query().Get("ref"))
+			rw.Header().Set("Content-Type", "application/json")
+			_, err := io.WriteString(rw, `{"path": ".github/CODEOWNERS", "content": "/src/ @a"}`)
+			require.NoError(t, err)
+			return
+		}
+		if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/api/v3/repos/%s/%s/git/trees/release", mockOwner, mockRepo) {
+			assert.Equal(t, "1", r.URL.Query().Get("recursive"))
+			rw.Header().Set("Content-Type", "application/json")
+			_, err := io.WriteString(rw, `{
+	"sha": "abc",
+	"tree": [
+		{"path": "src", "type": "tree"},
+		{"path": "src/a.go", "type": "blob"},
+		{"path": "lib", "type": "commit"},
+		{"path": "README.md", "type": "blob"}
+	],
+	"truncated": false
+}`)
+			require.NoError(t, err)
+			return
+		}
+		t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+	repo := &github.Repository{
+		Owner:         &github.User{Login: github.String(mockOwner)},
+		Name:          github.String(mockRepo),
+		DefaultBranch: github.String("main"),
+	}
+
+	got, err := GetRepoCoverage(context.Background(), mockGithubCli, repo, github.String("release"))
+
+	require.NoError(t, err)
+	assert.Equal(t, &Coverage{
+		Files:      2,
+		OwnedFiles: 1,
+		Dirs:       1,
+		OwnedDirs:  1,
+		Unowned:    []*UnownedTree{{Path: "README.md", Files: 1}},
+	}, got)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	diffContext = 3
+
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// UnifiedDiff returns unified diff from a to b of the file in path. It
+// returns empty string if there is no difference.
+func UnifiedDiff(path, a, b string) string {
+	if a == b {
+		return ""
+	}
+	d := difflib.UnifiedDiff{
+		A:        splitLines(a),
+		B:        splitLines(b),
+		FromFile: "a/" + path,
+		ToFile:   "b/" + path,
+		Context:  diffContext,
+	}
+	// it never fails since it writes to strings.Builder
+	s, _ := difflib.GetUnifiedDiffString(d)
+	return s
+}
+
+// splitLines splits s keeping line endings. Missing newline at the end of
+// file is marked as git does.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	ll := strings.SplitAfter(s, sep)
+	if ll[len(ll)-1] == "" {
+		return ll[:len(ll)-1]
+	}
+	ll[len(ll)-1] += sep + `\ No newline at end of file` + sep
+	return ll
+}
+
+// ColorizeDiff colors unified diff for terminal.
+func ColorizeDiff(diff string) string {
+	ll := strings.SplitAfter(diff, sep)
+	var b strings.Builder
+	for _, l := range ll {
+		if l == "" {
+			continue
+		}
+		color := ""
+		switch {
+		case strings.HasPrefix(l, "---"), strings.HasPrefix(l, "+++"):
+			color = colorBold
+		case strings.HasPrefix(l, "@@"):
+			color = colorCyan
+		case strings.HasPrefix(l, "-"):
+			color = colorRed
+		case strings.HasPrefix(l, "+"):
+			color = colorGreen
+		}
+		if color == "" {
+			b.WriteString(l)
+			continue
+		}
+		b.WriteString(color)
+		b.WriteString(strings.TrimSuffix(l, sep))
+		b.WriteString(colorReset)
+		if strings.HasSuffix(l, sep) {
+			b.WriteString(sep)
+		}
+	}
+	return b.String()
+}
+
+// isTerminal reports whether w is a terminal which accepts colors.
+func isTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
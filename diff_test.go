@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name:     "no difference",
+			a:        "* @a\n",
+			b:        "* @a\n",
+			expected: "",
+		},
+		{
+			name: "changed line",
+			a:    "# owners\n* @a\n/docs @c\n",
+			b:    "# owners\n* @b\n/docs @c\n",
+			expected: `--- a/CODEOWNERS
++++ b/CODEOWNERS
+@@ -1,3 +1,3 @@
+ # owners
+-* @a
++* @b
+ /docs @c
+`,
+		},
+		{
+			name: "no newline at end of file",
+			a:    "* @a",
+			b:    "* @b",
+			expected: `--- a/CODEOWNERS
++++ b/CODEOWNERS
+@@ -1 +1 @@
+-* @a
+\ No newline at end of file
++* @b
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := UnifiedDiff("CODEOWNERS", tc.a, tc.b)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestColorizeDiff(t *testing.T) {
+	given := "--- a/CODEOWNERS\n+++ b/CODEOWNERS\n@@ -1 +1 @@\n context\n-* @a\n+* @b\n"
+	expected := "\x1b[1m--- a/CODEOWNERS\x1b[0m\n" +
+		"\x1b[1m+++ b/CODEOWNERS\x1b[0m\n" +
+		"\x1b[36m@@ -1 +1 @@\x1b[0m\n" +
+		" context\n" +
+		"\x1b[31m-* @a\x1b[0m\n" +
+		"\x1b[32m+* @b\x1b[0m\n"
+
+	got := ColorizeDiff(given)
+
+	assert.Equal(t, expected, got)
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -16,27 +21,168 @@ const (
 	prBranch = "update-codeowners"
 
 	defaultPerPage = 100
+	// maxReviewedPRs bounds pull requests looked into for the last review.
+	maxReviewedPRs = 100
 )
 
 var (
 	ErrNotFound = errors.New("not found")
 )
 
-func NewGitHubClient(ctx context.Context, token string) *github.Client {
-	var httpClient *http.Client
+const (
+	defaultHost    = "github.com"
+	defaultAPIHost = "api." + defaultHost
+	defaultWebURL  = "https://" + defaultHost
+)
+
+// ClientOptions configures GitHub client.
+type ClientOptions struct {
+	// BaseURL is API base URL of GitHub Enterprise Server, e.g.
+	// "https://github.example.com/api/v3/". It's github.com if empty.
+	BaseURL string
+	// UploadURL is derived from BaseURL if empty.
+	UploadURL string
+	// CABundle is a PEM file of CA certificates trusted in addition to
+	// system ones.
+	CABundle string
+	Token    string
+	// App authenticates as an installation of GitHub App instead of Token.
+	App   *AppOptions
+	Retry RetryOptions
+}
 
-	if token != "" {
-		tokenSource := oauth2.StaticTokenSource(&oauth2.Token{
-			AccessToken: token,
+func NewGitHubClient(ctx context.Context, opt ClientOptions) (*github.Client, error) {
+	base := http.DefaultTransport
+	if opt.CABundle != "" {
+		t, err := newTransportWithCABundle(opt.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		base = t
+	}
+
+	// every worker shares the rate limit through the transport
+	var transport http.RoundTripper = newRateLimitTransport(base, opt.Retry)
+	switch {
+	case opt.App != nil:
+		appTransport, err := newAppTransport(*opt.App, base)
+		if err != nil {
+			return nil, err
+		}
+		appCli, err := newClient(opt, &http.Client{
+			Transport: appTransport,
 		})
-		httpClient = oauth2.NewClient(ctx, tokenSource)
+		if err != nil {
+			return nil, err
+		}
+		transport = &oauth2.Transport{
+			Source: newAppTokenSource(ctx, appCli, opt.App.InstallationID),
+			Base:   transport,
+		}
+	case opt.Token != "":
+		transport = &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{
+				AccessToken: opt.Token,
+			}),
+			Base: transport,
+		}
+	}
+
+	return newClient(opt, &http.Client{
+		Transport: transport,
+	})
+}
+
+func newClient(opt ClientOptions, httpClient *http.Client) (*github.Client, error) {
+	if isDotCom(opt.BaseURL) {
+		return github.NewClient(httpClient), nil
+	}
+	uploadURL := opt.UploadURL
+	if uploadURL == "" {
+		uploadURL = deriveUploadURL(opt.BaseURL)
 	}
-	return github.NewClient(httpClient)
+	cli, err := github.NewEnterpriseClient(opt.BaseURL, uploadURL, httpClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "github.NewEnterpriseClient")
+	}
+	return cli, nil
+}
+
+// deriveUploadURL returns upload URL of GitHub Enterprise Server from API base
+// URL. Base URL without /api/v3/ is returned as is, which go-github appends
+// api/uploads/ to.
+func deriveUploadURL(baseURL string) string {
+	trimmed := strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(trimmed, "/api/v3") {
+		return strings.TrimSuffix(trimmed, "/api/v3") + "/api/uploads/"
+	}
+	return baseURL
 }
 
-func ListActivatedRepositories(ctx context.Context, cli *github.Client, owner string) ([]*github.Repository, error) {
+func newTransportWithCABundle(path string) (*http.Transport, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile")
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificate found in %s", path)
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	return t, nil
+}
+
+// isDotCom reports whether API base URL is of github.com.
+func isDotCom(baseURL string) bool {
+	if baseURL == "" {
+		return true
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, defaultAPIHost)
+}
+
+// webHost returns host of GitHub for API base URL, e.g. "github.com" or
+// "github.example.com".
+func webHost(baseURL string) string {
+	if isDotCom(baseURL) {
+		return defaultHost
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// WebURL returns web URL of GitHub host which the client calls, e.g.
+// "https://github.com" or "https://github.example.com".
+func WebURL(cli *github.Client) string {
+	if cli.BaseURL == nil || strings.EqualFold(cli.BaseURL.Host, defaultAPIHost) {
+		return defaultWebURL
+	}
+	u := *cli.BaseURL
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3")
+	u.RawPath = ""
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// ListActivatedRepositories returns repositories of the organization or the
+// user account selected by sel. Nil sel selects every non-archived private
+// repository of the organization, or every one of the user.
+func ListActivatedRepositories(ctx context.Context, cli *github.Client, owner string, sel *RepoSelector) ([]*github.Repository, error) {
 	opt := &github.RepositoryListByOrgOptions{
-		Type: "private",
+		Type: sel.listType(),
 		ListOptions: github.ListOptions{
 			Page:    0,
 			PerPage: defaultPerPage,
@@ -47,6 +193,10 @@ func ListActivatedRepositories(ctx context.Context, cli *github.Client, owner st
 	for {
 		rr, resp, err := cli.Repositories.ListByOrg(ctx, owner, opt)
 		if err != nil {
+			// owner is not an organization
+			if resp != nil && resp.StatusCode == http.StatusNotFound && opt.Page == 0 {
+				return listUserRepositories(ctx, cli, owner, sel)
+			}
 			return nil, errors.Wrap(err, "cli.Repositories.ListByOrg")
 		}
 		allRepos = append(allRepos, rr...)
@@ -58,72 +208,212 @@ func ListActivatedRepositories(ctx context.Context, cli *github.Client, owner st
 		opt.ListOptions.Page = resp.NextPage
 	}
 
-	filtered := make([]*github.Repository, 0, len(allRepos))
-	for _, r := range allRepos {
-		if r.GetArchived() {
-			continue
+	return sel.Filter(allRepos), nil
+}
+
+// listUserRepositories returns repositories owned by the user. Private ones
+// are listed only if the user is the authenticated one, since GitHub lists
+// only public repositories of the others. Unlike organization, visibility is
+// filtered after listing.
+func listUserRepositories(ctx context.Context, cli *github.Client, user string, sel *RepoSelector) ([]*github.Repository, error) {
+	opt := &github.RepositoryListOptions{
+		Type: "owner",
+		ListOptions: github.ListOptions{
+			Page:    0,
+			PerPage: defaultPerPage,
+		},
+	}
+	me, err := authenticatedLogin(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(me, user) {
+		// GET /user/repos rejects type with affiliation
+		opt.Type = ""
+		opt.Affiliation = "owner"
+		user = ""
+	}
+
+	var allRepos []*github.Repository
+	for {
+		rr, resp, err := cli.Repositories.List(ctx, user, opt)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, errors.Wrap(ErrNotFound, "cli.Repositories.List")
+			}
+			return nil, errors.Wrap(err, "cli.Repositories.List")
+		}
+		for _, r := range rr {
+			if sel.matchVisibility(r) {
+				allRepos = append(allRepos, r)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
 		}
-		filtered = append(filtered, r)
+
+		opt.ListOptions.Page = resp.NextPage
 	}
 
-	return filtered, nil
+	return sel.Filter(allRepos), nil
 }
 
-func GetCodeownersContent(ctx context.Context, cli *github.Client, r *github.Repository) (*github.RepositoryContent, error) {
-	// If codeowner updating branch is already exist, use it's ref
-	exist, err := isBranchExists(ctx, cli, r, prBranch)
+// authenticatedLogin returns login of the authenticated user. It returns
+// empty string if the client isn't authenticated as a user, e.g. as GitHub
+// App installation.
+func authenticatedLogin(ctx context.Context, cli *github.Client) (string, error) {
+	u, resp, err := cli.Users.Get(ctx, "")
 	if err != nil {
-		return nil, err
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "cli.Users.Get")
 	}
+	return u.GetLogin(), nil
+}
 
-	var ref *string
-	if exist {
-		ref = github.String("refs/heads/" + prBranch)
-	}
+// codeownersPaths are locations of CODEOWNERS file in order of precedence.
+// GitHub uses the first one found and ignores the others.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
 
-	fc, err := getContent(ctx, cli, r, ".github/CODEOWNERS", ref)
+// GetCodeownersContent returns the effective CODEOWNERS file in the branch
+// updating codeowners if it exists, or in the default branch.
+func GetCodeownersContent(ctx context.Context, cli *github.Client, r *github.Repository, branch string) (*github.RepositoryContent, error) {
+	ref, err := codeownersRef(ctx, cli, r, branch)
 	if err != nil {
+		return nil, err
+	}
+	return GetCodeownersContentAt(ctx, cli, r, ref)
+}
+
+// GetCodeownersContentAt returns the effective CODEOWNERS file at ref. The
+// default branch is used if ref is nil.
+func GetCodeownersContentAt(ctx context.Context, cli *github.Client, r *github.Repository, ref *string) (*github.RepositoryContent, error) {
+	for _, p := range codeownersPaths {
+		fc, err := getContent(ctx, cli, r, p, ref)
 		if errors.Cause(err) == ErrNotFound {
-			fc, err := getContent(ctx, cli, r, "CODEOWNERS", ref)
-			if err != nil {
-				return nil, err
-			}
-			return fc, nil
+			continue
 		}
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+		return fc, nil
 	}
-	return fc, nil
+	return nil, errors.Wrap(ErrNotFound, "GetCodeownersContentAt")
 }
 
-func CreatePatch(ctx context.Context, cli *github.Client, r *github.Repository, old *github.RepositoryContent, newContent string, commitMsg *string) error {
-	var (
-		owner = r.GetOwner().GetLogin()
-		name  = r.GetName()
-	)
-	exist, err := isBranchExists(ctx, cli, r, prBranch)
+// ListCodeownersContents returns every CODEOWNERS file in order of
+// precedence, so that the first one is effective and the others are ignored.
+// The branch updating codeowners is read if it exists.
+func ListCodeownersContents(ctx context.Context, cli *github.Client, r *github.Repository, branch string) ([]*github.RepositoryContent, error) {
+	ref, err := codeownersRef(ctx, cli, r, branch)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !exist {
-		mainRef, _, err := cli.Git.GetRef(ctx, owner, name, "refs/heads/"+r.GetDefaultBranch())
+	return ListCodeownersContentsAt(ctx, cli, r, ref)
+}
+
+// ListCodeownersContentsAt returns every CODEOWNERS file at ref in order of
+// precedence. The default branch is used if ref is nil.
+func ListCodeownersContentsAt(ctx context.Context, cli *github.Client, r *github.Repository, ref *string) ([]*github.RepositoryContent, error) {
+	var all []*github.RepositoryContent
+	for _, p := range codeownersPaths {
+		fc, err := getContent(ctx, cli, r, p, ref)
+		if errors.Cause(err) == ErrNotFound {
+			continue
+		}
 		if err != nil {
-			return errors.Wrap(err, "cli.Git.GetRef")
+			return nil, err
 		}
+		all = append(all, fc)
+	}
+	if len(all) == 0 {
+		return nil, errors.Wrap(ErrNotFound, "ListCodeownersContentsAt")
+	}
+	return all, nil
+}
 
-		prRef := &github.Reference{
-			Ref: github.String("refs/heads/" + prBranch),
-			Object: &github.GitObject{
-				SHA: mainRef.Object.SHA,
-			},
+// GetRepository returns repository of "owner/name".
+func GetRepository(ctx context.Context, cli *github.Client, fullName string) (*github.Repository, error) {
+	owner, name, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, errors.Errorf("invalid repository %q, expected owner/name", fullName)
+	}
+	r, res, err := cli.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, errors.Wrap(ErrNotFound, "cli.Repositories.Get")
 		}
+		return nil, errors.Wrap(err, "cli.Repositories.Get")
+	}
+	return r, nil
+}
 
-		if _, _, err := cli.Git.CreateRef(ctx, owner, name, prRef); err != nil {
-			return errors.Wrap(err, "cli.Git.CreateRef")
+// ListFiles returns paths of every file in the repository at ref. The default
+// branch is used if ref is nil.
+func ListFiles(ctx context.Context, cli *github.Client, r *github.Repository, ref *string) ([]string, error) {
+	sha := r.GetDefaultBranch()
+	if ref != nil {
+		sha = *ref
+	}
+
+	tree, res, err := cli.Git.GetTree(ctx, r.GetOwner().GetLogin(), r.GetName(), sha, true)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, errors.Wrap(ErrNotFound, "cli.Git.GetTree")
+		}
+		// empty repository has no tree
+		if res != nil && res.StatusCode == http.StatusConflict {
+			return nil, nil
 		}
-		log.Info("success to create ref")
+		return nil, errors.Wrap(err, "cli.Git.GetTree")
 	}
-	if exist {
-		log.Infof("skipped creation because of already exist branch")
+	// GitHub truncates tree over 100,000 entries
+	if tree.GetTruncated() {
+		return nil, errors.Errorf("tree of %s is truncated", r.GetFullName())
+	}
+
+	var paths []string
+	for _, e := range tree.Entries {
+		if e.GetType() != "blob" {
+			continue
+		}
+		paths = append(paths, e.GetPath())
+	}
+	return paths, nil
+}
+
+// GetCommitSHA returns SHA of the commit which ref points to.
+func GetCommitSHA(ctx context.Context, cli *github.Client, r *github.Repository, ref string) (string, error) {
+	sha, _, err := cli.Repositories.GetCommitSHA1(ctx, r.GetOwner().GetLogin(), r.GetName(), ref, "")
+	if err != nil {
+		return "", errors.Wrap(err, "cli.Repositories.GetCommitSHA1")
+	}
+	return sha, nil
+}
+
+// codeownersRef returns ref of codeowner updating branch if it already exists.
+// Otherwise it returns nil for the default branch, as well as if branch is
+// empty.
+func codeownersRef(ctx context.Context, cli *github.Client, r *github.Repository, branch string) (*string, error) {
+	if branch == "" {
+		return nil, nil
+	}
+	exist, err := isBranchExists(ctx, cli, r, branch)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, nil
+	}
+	return github.String("refs/heads/" + branch), nil
+}
+
+// CreatePatch commits new content of the file to codeowner updating branch.
+func CreatePatch(ctx context.Context, cli *github.Client, r *github.Repository, branch string, old *github.RepositoryContent, newContent string, commitMsg *string) error {
+	if err := createBranch(ctx, cli, r, branch); err != nil {
+		return err
 	}
 
 	if commitMsg == nil {
@@ -133,15 +423,67 @@ func CreatePatch(ctx context.Context, cli *github.Client, r *github.Repository,
 		Message: commitMsg,
 		Content: []byte(newContent),
 		SHA:     github.String(old.GetSHA()),
-		Branch:  github.String(prBranch),
+		Branch:  github.String(branch),
 	}
-	if _, _, err := cli.Repositories.CreateFile(ctx, owner, name, old.GetPath(), opt); err != nil {
+	if _, _, err := cli.Repositories.CreateFile(ctx, r.GetOwner().GetLogin(), r.GetName(), old.GetPath(), opt); err != nil {
 		return errors.Wrap(err, "cli.Repositories.CreateFile")
 	}
 	return nil
 }
 
-func OpenPR(ctx context.Context, cli *github.Client, r *github.Repository, prTitle, head, body string, reviewReq *github.ReviewersRequest) (*github.PullRequest, error) {
+// DeleteContent deletes the file in codeowner updating branch.
+func DeleteContent(ctx context.Context, cli *github.Client, r *github.Repository, branch string, fc *github.RepositoryContent, commitMsg string) error {
+	if err := createBranch(ctx, cli, r, branch); err != nil {
+		return err
+	}
+
+	opt := &github.RepositoryContentFileOptions{
+		Message: github.String(commitMsg),
+		SHA:     github.String(fc.GetSHA()),
+		Branch:  github.String(branch),
+	}
+	if _, _, err := cli.Repositories.DeleteFile(ctx, r.GetOwner().GetLogin(), r.GetName(), fc.GetPath(), opt); err != nil {
+		return errors.Wrap(err, "cli.Repositories.DeleteFile")
+	}
+	return nil
+}
+
+// createBranch creates codeowner updating branch from the default branch
+// unless it already exists.
+func createBranch(ctx context.Context, cli *github.Client, r *github.Repository, branch string) error {
+	var (
+		owner = r.GetOwner().GetLogin()
+		name  = r.GetName()
+	)
+	exist, err := isBranchExists(ctx, cli, r, branch)
+	if err != nil {
+		return err
+	}
+	if exist {
+		log.Infof("skipped creation because of already exist branch")
+		return nil
+	}
+
+	mainRef, _, err := cli.Git.GetRef(ctx, owner, name, "refs/heads/"+r.GetDefaultBranch())
+	if err != nil {
+		return errors.Wrap(err, "cli.Git.GetRef")
+	}
+
+	prRef := &github.Reference{
+		Ref: github.String("refs/heads/" + branch),
+		Object: &github.GitObject{
+			SHA: mainRef.Object.SHA,
+		},
+	}
+
+	if _, _, err := cli.Git.CreateRef(ctx, owner, name, prRef); err != nil {
+		return errors.Wrap(err, "cli.Git.CreateRef")
+	}
+	log.Info("success to create ref")
+	return nil
+}
+
+func OpenPR(ctx context.Context, cli *github.Client, r *github.Repository, prTitle, head, body string, reviewReq *github.ReviewersRequest, labels []string) (*github.PullRequest, error) {
 	req := &github.NewPullRequest{
 		Title: github.String(prTitle),
 		Head:  github.String(head),
@@ -155,12 +497,6 @@ func OpenPR(ctx context.Context, cli *github.Client, r *github.Repository, prTit
 		if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity && strings.Contains(err.Error(), "A pull request already exists") {
 			return nil, nil
 		}
-		if resp != nil && resp.StatusCode == http.StatusForbidden && strings.Contains(err.Error(), "You have exceeded a secondary rate limit") {
-			log.WithField("repo", r.GetName()).Info("waiting rate limit")
-			// TODO: Provide cli option
-			time.Sleep(1 * time.Minute)
-			return OpenPR(ctx, cli, r, prTitle, head, body, reviewReq)
-		}
 		return nil, errors.Wrap(err, "cli.PullRequests.Create")
 	}
 
@@ -169,6 +505,11 @@ func OpenPR(ctx context.Context, cli *github.Client, r *github.Repository, prTit
 			return nil, errors.Wrap(err, "cli.PullRequests.RequestReviewers")
 		}
 	}
+	if len(labels) > 0 {
+		if _, _, err := cli.Issues.AddLabelsToIssue(ctx, r.GetOwner().GetLogin(), r.GetName(), pr.GetNumber(), labels); err != nil {
+			return nil, errors.Wrap(err, "cli.Issues.AddLabelsToIssue")
+		}
+	}
 	return pr, nil
 }
 
@@ -222,6 +563,385 @@ func ListTeams(ctx context.Context, cli *github.Client, owner string) ([]*github
 	return all, nil
 }
 
+// ListTeamMembers returns members of the team of org including members of
+// its child teams.
+func ListTeamMembers(ctx context.Context, cli *github.Client, org, slug string) ([]*github.User, error) {
+	opt := &github.TeamListTeamMembersOptions{
+		ListOptions: github.ListOptions{
+			Page:    0,
+			PerPage: defaultPerPage,
+		},
+	}
+
+	var all []*github.User
+	for {
+		uu, resp, err := cli.Teams.ListTeamMembersBySlug(ctx, org, slug, opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "cli.Teams.ListTeamMembersBySlug")
+		}
+		all = append(all, uu...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.ListOptions.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// GetUser returns the user or the organization of the login.
+func GetUser(ctx context.Context, cli *github.Client, login string) (*github.User, error) {
+	u, res, err := cli.Users.Get(ctx, login)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, errors.Wrap(ErrNotFound, "cli.Users.Get")
+		}
+		return nil, errors.Wrap(err, "cli.Users.Get")
+	}
+	return u, nil
+}
+
+// IsUserSuspended reports whether the user is suspended. It returns
+// ErrNotFound if the user doesn't exist.
+func IsUserSuspended(ctx context.Context, cli *github.Client, login string) (bool, error) {
+	u, res, err := cli.Users.Get(ctx, login)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return false, errors.Wrap(ErrNotFound, "cli.Users.Get")
+		}
+		return false, errors.Wrap(err, "cli.Users.Get")
+	}
+
+	return u.SuspendedAt != nil, nil
+}
+
+// LastCommitAt returns when the latest commit of the user on the default
+// branch since the time was authored. It returns zero time if nothing is
+// found, and since is ignored if it's zero.
+func LastCommitAt(ctx context.Context, cli *github.Client, r *github.Repository, login string, since time.Time) (time.Time, error) {
+	opt := &github.CommitsListOptions{
+		Author: login,
+		Since:  since,
+		ListOptions: github.ListOptions{
+			PerPage: 1,
+		},
+	}
+	cc, res, err := cli.Repositories.ListCommits(ctx, r.GetOwner().GetLogin(), r.GetName(), opt)
+	if err != nil {
+		// empty repository has no commit
+		if res != nil && res.StatusCode == http.StatusConflict {
+			return time.Time{}, nil
+		}
+		return time.Time{}, errors.Wrap(err, "cli.Repositories.ListCommits")
+	}
+	if len(cc) == 0 {
+		return time.Time{}, nil
+	}
+
+	return cc[0].GetCommit().GetAuthor().GetDate(), nil
+}
+
+// LastReviewAt returns when the user last submitted a review of pull request
+// since the time. It returns zero time if nothing is found, and since is
+// ignored if it's zero. Pull requests reviewed by the user are searched in
+// order of update, which is never before their reviews, and at most
+// maxReviewedPRs of them are looked into.
+func LastReviewAt(ctx context.Context, cli *github.Client, r *github.Repository, login string, since time.Time) (time.Time, error) {
+	q := fmt.Sprintf("repo:%s/%s type:pr reviewed-by:%s", r.GetOwner().GetLogin(), r.GetName(), login)
+	if !since.IsZero() {
+		q += " updated:>=" + since.UTC().Format("2006-01-02")
+	}
+	opt := &github.SearchOptions{
+		Sort:  "updated",
+		Order: "desc",
+		ListOptions: github.ListOptions{
+			PerPage: defaultPerPage,
+		},
+	}
+
+	var last time.Time
+	for checked := 0; checked < maxReviewedPRs; {
+		res, resp, err := cli.Search.Issues(ctx, q, opt)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "cli.Search.Issues")
+		}
+		for _, pr := range res.Issues {
+			// later ones can't have been reviewed after the last review
+			if checked >= maxReviewedPRs || pr.GetUpdatedAt().Before(last) {
+				return sinceOrZero(last, since), nil
+			}
+			checked++
+			at, err := lastReviewOf(ctx, cli, r, pr.GetNumber(), login)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if at.After(last) {
+				last = at
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return sinceOrZero(last, since), nil
+}
+
+// lastReviewOf returns when the user last submitted a review of the pull
+// request, or zero time if never.
+func lastReviewOf(ctx context.Context, cli *github.Client, r *github.Repository, number int, login string) (time.Time, error) {
+	opt := &github.ListOptions{
+		PerPage: defaultPerPage,
+	}
+
+	var last time.Time
+	for {
+		reviews, resp, err := cli.PullRequests.ListReviews(ctx, r.GetOwner().GetLogin(), r.GetName(), number, opt)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "cli.PullRequests.ListReviews")
+		}
+		for _, rv := range reviews {
+			if strings.EqualFold(rv.GetUser().GetLogin(), login) && rv.GetSubmittedAt().After(last) {
+				last = rv.GetSubmittedAt()
+			}
+		}
+		if resp.NextPage == 0 {
+			return last, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// sinceOrZero returns zero time if t is before since.
+func sinceOrZero(t, since time.Time) time.Time {
+	if t.Before(since) {
+		return time.Time{}
+	}
+	return t
+}
+
+// ListCommitsSince returns at most limit commits on the default branch since
+// the time, in order of newest first.
+func ListCommitsSince(ctx context.Context, cli *github.Client, r *github.Repository, since time.Time, limit int) ([]*github.RepositoryCommit, error) {
+	opt := &github.CommitsListOptions{
+		Since: since,
+		ListOptions: github.ListOptions{
+			Page:    0,
+			PerPage: defaultPerPage,
+		},
+	}
+
+	var all []*github.RepositoryCommit
+	for len(all) < limit {
+		cc, resp, err := cli.Repositories.ListCommits(ctx, r.GetOwner().GetLogin(), r.GetName(), opt)
+		if err != nil {
+			// empty repository has no commit
+			if resp != nil && resp.StatusCode == http.StatusConflict {
+				return nil, nil
+			}
+			return nil, errors.Wrap(err, "cli.Repositories.ListCommits")
+		}
+		all = append(all, cc...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.ListOptions.Page = resp.NextPage
+	}
+	if len(all) > limit {
+		all = all[:limit]
+	}
+
+	return all, nil
+}
+
+// GetCommitFiles returns paths of files changed by the commit.
+func GetCommitFiles(ctx context.Context, cli *github.Client, r *github.Repository, sha string) ([]string, error) {
+	c, _, err := cli.Repositories.GetCommit(ctx, r.GetOwner().GetLogin(), r.GetName(), sha, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cli.Repositories.GetCommit")
+	}
+
+	paths := make([]string, len(c.Files))
+	for i, f := range c.Files {
+		paths[i] = f.GetFilename()
+	}
+	return paths, nil
+}
+
+// ListClosedPullRequestsSince returns at most limit closed pull requests
+// updated since the time, in order of recently updated.
+func ListClosedPullRequestsSince(ctx context.Context, cli *github.Client, r *github.Repository, since time.Time, limit int) ([]*github.PullRequest, error) {
+	opt := &github.PullRequestListOptions{
+		State:     "closed",
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			Page:    0,
+			PerPage: defaultPerPage,
+		},
+	}
+
+	var all []*github.PullRequest
+	for {
+		pp, resp, err := cli.PullRequests.List(ctx, r.GetOwner().GetLogin(), r.GetName(), opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "cli.PullRequests.List")
+		}
+		for _, p := range pp {
+			if p.GetUpdatedAt().Before(since) || len(all) >= limit {
+				return all, nil
+			}
+			all = append(all, p)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.ListOptions.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// ListPullRequestFiles returns paths of files changed by the pull request.
+func ListPullRequestFiles(ctx context.Context, cli *github.Client, r *github.Repository, number int) ([]string, error) {
+	opt := &github.ListOptions{
+		Page:    0,
+		PerPage: defaultPerPage,
+	}
+
+	var all []string
+	for {
+		ff, resp, err := cli.PullRequests.ListFiles(ctx, r.GetOwner().GetLogin(), r.GetName(), number, opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "cli.PullRequests.ListFiles")
+		}
+		for _, f := range ff {
+			all = append(all, f.GetFilename())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// ListReviewers returns logins of users who reviewed the pull request, in
+// order of their first review.
+func ListReviewers(ctx context.Context, cli *github.Client, r *github.Repository, number int) ([]string, error) {
+	opt := &github.ListOptions{
+		Page:    0,
+		PerPage: defaultPerPage,
+	}
+
+	var all []string
+	for {
+		rr, resp, err := cli.PullRequests.ListReviews(ctx, r.GetOwner().GetLogin(), r.GetName(), number, opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "cli.PullRequests.ListReviews")
+		}
+		for _, review := range rr {
+			all = append(all, review.GetUser().GetLogin())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return set(all), nil
+}
+
+// ListWritableTeams returns teams having push, maintain or admin permission
+// on the repository.
+func ListWritableTeams(ctx context.Context, cli *github.Client, r *github.Repository) ([]*github.Team, error) {
+	opt := &github.ListOptions{
+		Page:    0,
+		PerPage: defaultPerPage,
+	}
+
+	var all []*github.Team
+	for {
+		tt, resp, err := cli.Repositories.ListTeams(ctx, r.GetOwner().GetLogin(), r.GetName(), opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "cli.Repositories.ListTeams")
+		}
+		for _, t := range tt {
+			switch t.GetPermission() {
+			case "push", "maintain", "admin":
+				all = append(all, t)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// FindUserByEmail returns the user whose public profile email is email.
+// Private and commit emails aren't searchable. It returns ErrNotFound unless
+// exactly one user is found.
+func FindUserByEmail(ctx context.Context, cli *github.Client, email string) (*github.User, error) {
+	res, _, err := cli.Search.Users(ctx, email+" in:email", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cli.Search.Users")
+	}
+	if len(res.Users) != 1 {
+		return nil, errors.Wrap(ErrNotFound, "cli.Search.Users")
+	}
+
+	return res.Users[0], nil
+}
+
+// HasTeamWriteAccess reports whether the team of org has push, maintain or
+// admin permission on the repository, including one inherited from its parent.
+func HasTeamWriteAccess(ctx context.Context, cli *github.Client, r *github.Repository, org, slug string) (bool, error) {
+	repo, res, err := cli.Teams.IsTeamRepoBySlug(ctx, org, slug, r.GetOwner().GetLogin(), r.GetName())
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "cli.Teams.IsTeamRepoBySlug")
+	}
+
+	perms := repo.GetPermissions()
+	return perms["push"] || perms["maintain"] || perms["admin"], nil
+}
+
+// HasUserWriteAccess reports whether the user has write or admin permission
+// on the repository.
+func HasUserWriteAccess(ctx context.Context, cli *github.Client, r *github.Repository, user string) (bool, error) {
+	level, res, err := cli.Repositories.GetPermissionLevel(ctx, r.GetOwner().GetLogin(), r.GetName(), user)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "cli.Repositories.GetPermissionLevel")
+	}
+
+	switch level.GetPermission() {
+	case "admin", "write":
+		return true, nil
+	}
+	return false, nil
+}
+
 func isBranchExists(ctx context.Context, cli *github.Client, r *github.Repository, branch string) (bool, error) {
 	_, res, err := cli.Repositories.GetBranch(ctx, r.GetOwner().GetLogin(), r.GetName(), branch, true)
 	if err != nil {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGitHubClient(t *testing.T) {
+	const mockOwner = "some-org"
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/api/v3/orgs/%s/repos", mockOwner) {
+			assert.Equal(t, "Bearer some-token", r.Header.Get("Authorization"))
+			rw.Header().Set("Content-Type", "application/json")
+			_, err := io.WriteString(rw, `[{"name": "some-repo"}]`)
+			require.NoError(t, err)
+			return
+		}
+		t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+	}))
+	// untrusted certificate makes handshake error logs
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+	require.NoError(t, os.WriteFile(caBundle, certPEM, 0o600))
+
+	t.Run("enterprise server with CA bundle", func(t *testing.T) {
+		cli, err := NewGitHubClient(context.Background(), ClientOptions{
+			BaseURL:  server.URL,
+			CABundle: caBundle,
+			Token:    "some-token",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/api/v3/", cli.BaseURL.String())
+		assert.Equal(t, server.URL+"/api/uploads/", cli.UploadURL.String())
+
+		repos, err := ListActivatedRepositories(context.Background(), cli, mockOwner, nil)
+
+		require.NoError(t, err)
+		require.Len(t, repos, 1)
+		assert.Equal(t, "some-repo", repos[0].GetName())
+	})
+
+	t.Run("enterprise server with api v3 URL", func(t *testing.T) {
+		cli, err := NewGitHubClient(context.Background(), ClientOptions{
+			BaseURL:  server.URL + "/api/v3/",
+			CABundle: caBundle,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/api/v3/", cli.BaseURL.String())
+		assert.Equal(t, server.URL+"/api/uploads/", cli.UploadURL.String())
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		cli, err := NewGitHubClient(context.Background(), ClientOptions{
+			BaseURL: server.URL,
+		})
+		require.NoError(t, err)
+
+		_, err = ListActivatedRepositories(context.Background(), cli, mockOwner, nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "invalid.pem")
+		require.NoError(t, os.WriteFile(invalid, []byte("invalid"), 0o600))
+
+		_, err := NewGitHubClient(context.Background(), ClientOptions{
+			BaseURL:  server.URL,
+			CABundle: invalid,
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWebURL(t *testing.T) {
+	cases := []struct {
+		name     string
+		baseURL  string
+		expected string
+	}{
+		{
+			name:     "github.com",
+			baseURL:  "",
+			expected: "https://github.com",
+		},
+		{
+			name:     "explicit github.com",
+			baseURL:  "https://api.github.com/",
+			expected: "https://github.com",
+		},
+		{
+			name:     "enterprise server",
+			baseURL:  "https://github.example.com",
+			expected: "https://github.example.com",
+		},
+		{
+			name:     "enterprise server with api path",
+			baseURL:  "https://github.example.com/api/v3/",
+			expected: "https://github.example.com",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli, err := NewGitHubClient(context.Background(), ClientOptions{
+				BaseURL: tc.baseURL,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, WebURL(cli))
+		})
+	}
+
+	t.Run("default client", func(t *testing.T) {
+		assert.Equal(t, "https://github.com", WebURL(github.NewClient(nil)))
+	})
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v48/github"
 	"github.com/pkg/errors"
@@ -11,37 +13,642 @@ import (
 
 type Codeowner struct {
 	Name     string
+	Kind     OwnerKind
 	OwnRepos []string
+	// Successor is an existing owner suggested to replace the missing one,
+	// e.g. the team guessed by name to have had the deleted team nested.
+	Successor string
+	// Occurrences are every rule of the owner in OwnRepos.
+	Occurrences []*Occurrence
 }
 
-func Inspect(ctx context.Context, cli *github.Client, owner string) ([]*Codeowner, error) {
-	users, err := listMemberNames(ctx, cli, owner)
+// Occurrence is a rule where an owner is written.
+type Occurrence struct {
+	Repo string `json:"repo" yaml:"repo"`
+	Path string `json:"path" yaml:"path"`
+	// Ref is the branch the file is read from.
+	Ref     string `json:"ref" yaml:"ref"`
+	Line    int    `json:"line" yaml:"line"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Permalink is URL to the line at the commit, or at Ref if the commit is
+	// unknown.
+	Permalink string `json:"permalink" yaml:"permalink"`
+}
+
+// InspectOptions controls how Inspect works.
+type InspectOptions struct {
+	Pool PoolOptions
+	// Repos selects repositories to inspect.
+	Repos *RepoSelector
+	// IgnoreOwners are never reported.
+	IgnoreOwners []string
+	// Branch is the branch updating codeowners, which is read instead of
+	// the default branch if it exists. Only the default branch is read if
+	// empty.
+	Branch string
+	// Shadowed looks up every CODEOWNERS location to report ignored ones.
+	Shadowed bool
+	// Permissions checks whether existing owners have write access to each
+	// repository they own, since GitHub ignores owners without it.
+	Permissions bool
+	// Teams checks members of team owners to report weak teams.
+	Teams bool
+	// DirectMembers counts only direct members of the team when checking weak
+	// teams. Otherwise members of child teams are counted as GitHub lists
+	// them.
+	DirectMembers bool
+	// Stale checks commits and reviews of user owners in each repository
+	// they own within StaleWindow, defaultStaleWindow if not positive.
+	Stale       bool
+	StaleWindow time.Duration
+}
+
+// Report is a result of Inspect.
+type Report struct {
+	// Owners should be replaced.
+	Owners []*Codeowner
+	// Shadowed are repositories having CODEOWNERS files ignored by GitHub.
+	Shadowed []*RepoCodeowners
+	// Ineffective owners exist but lack write access to OwnRepos, so their
+	// reviews are never requested there.
+	Ineffective []*Codeowner
+	// External owners are teams of other organizations, which can't be
+	// looked up and have no access to OwnRepos.
+	External []*Codeowner
+	// WeakTeams exist but give little review coverage.
+	WeakTeams []*WeakTeam
+	// Stale owners have no activity in repositories they own.
+	Stale []*StaleOwner
+	// Repos are every repository having CODEOWNERS file.
+	Repos []*RepoCodeowners
+}
+
+// TeamIssue is a reason why a team gives little review coverage.
+type TeamIssue int
+
+const (
+	// EmptyTeam has no member.
+	EmptyTeam TeamIssue = iota + 1
+	// InactiveTeam has only members missing in organization or suspended.
+	InactiveTeam
+	// SingleMemberTeam has only one active member.
+	SingleMemberTeam
+)
+
+func (i TeamIssue) String() string {
+	switch i {
+	case EmptyTeam:
+		return "empty"
+	case InactiveTeam:
+		return "no active member"
+	case SingleMemberTeam:
+		return "single member"
+	}
+	return "unknown"
+}
+
+// WeakTeam is a team owner giving little review coverage.
+type WeakTeam struct {
+	Name  string
+	Issue TeamIssue
+	// Parent is the parent team as "org/slug" if the team is nested.
+	Parent string
+	// Members are logins of members of the team counted.
+	Members  []string
+	OwnRepos []string
+}
+
+// RepoCodeowners is the effective CODEOWNERS file of a repository.
+type RepoCodeowners struct {
+	Repo *github.Repository
+	Path string
+	// Ref is the branch the file is read from, and SHA is the commit it
+	// points to. SHA is resolved only for repositories in the report.
+	Ref  string
+	SHA  string
+	File *File
+	// Shadowed are paths of CODEOWNERS files ignored by GitHub since Path
+	// takes precedence.
+	Shadowed []string
+}
+
+// Inspect reports problems of codeowners of the organization or the user
+// account. Users of organization are known if they are its members, while
+// users of user account are known if they exist. Teams of other
+// organizations are reported as external.
+func Inspect(ctx context.Context, cli *github.Client, owner string, opt InspectOptions) (*Report, error) {
+	account, err := GetUser(ctx, cli, owner)
+	if err != nil {
+		return nil, errors.Wrap(err, owner)
+	}
+	isOrg := account.GetType() == "Organization"
+
+	var users []string
+	tree := NewTeamTree(owner, nil)
+	if isOrg {
+		users, err = listMemberNames(ctx, cli, owner)
+		if err != nil {
+			return nil, err
+		}
+		tree, err = loadTeamTree(ctx, cli, owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+	teams := tree.Names()
+
+	all, err := listAllCodeowners(ctx, cli, owner, opt)
+	if err != nil {
+		return nil, err
+	}
+	ownerMapByName := groupByCodeowner(ownersByRepo(all))
+	names := make([]string, 0, len(ownerMapByName))
+	for k := range ownerMapByName {
+		names = append(names, k)
+	}
+	names = diff(names, opt.IgnoreOwners)
+	sort.Strings(names)
+
+	if !isOrg {
+		users, err = listExistingUsers(ctx, cli, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+	emails := make([]string, 0)
+	for _, n := range names {
+		if ownerKind(n) == EmailOwner {
+			emails = append(emails, n)
+		}
+	}
+	emailUsers := users
+	if !isOrg {
+		// email of any user is valid in repositories of user account
+		emailUsers = nil
+	}
+	memberEmails, err := listMemberEmails(ctx, cli, emails, emailUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	known := append(users, teams...)
+	known = append(known, memberEmails...)
+	diffNames := diff(names, known)
+	missing, external := splitExternal(owner, diffNames)
+	// missing, external and ignored owners are not checked further
+	skip := append(append([]string{}, diffNames...), opt.IgnoreOwners...)
+
+	report := &Report{
+		Owners: make([]*Codeowner, len(missing)),
+		Repos:  all,
+	}
+	for i, n := range missing {
+		o := ownerMapByName[n]
+		if o.Kind == TeamOwner {
+			_, slug, _ := strings.Cut(o.Name, "/")
+			if p := tree.GuessParent(slug); p != "" {
+				o.Successor = owner + "/" + p
+			}
+		}
+		report.Owners[i] = o
+	}
+	for _, n := range external {
+		report.External = append(report.External, ownerMapByName[n])
+	}
+	for _, rc := range all {
+		if len(rc.Shadowed) > 0 {
+			report.Shadowed = append(report.Shadowed, rc)
+		}
+	}
+	if opt.Permissions {
+		report.Ineffective, err = listIneffectiveOwners(ctx, cli, all, skip, opt.Pool)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opt.Teams {
+		teamOwners := make([]*Codeowner, 0)
+		for _, n := range diff(names, diffNames) {
+			if o := ownerMapByName[n]; o.Kind == TeamOwner {
+				teamOwners = append(teamOwners, o)
+			}
+		}
+		report.WeakTeams, err = listWeakTeams(ctx, cli, teamOwners, users, tree, opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opt.Stale {
+		window := opt.StaleWindow
+		if window <= 0 {
+			window = defaultStaleWindow
+		}
+		report.Stale, err = listStaleOwners(ctx, cli, all, skip, time.Now().Add(-window), opt.Pool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := resolveCommits(ctx, cli, all, report, opt.Pool); err != nil {
+		return nil, err
+	}
+	webURL := WebURL(cli)
+	for _, o := range report.codeowners() {
+		o.Occurrences = listOccurrences(all, o.Name, o.OwnRepos, webURL)
+	}
+	return report, nil
+}
+
+// codeowners returns every owner having OwnRepos and Occurrences.
+func (r *Report) codeowners() []*Codeowner {
+	oo := append([]*Codeowner{}, r.Owners...)
+	oo = append(oo, r.Ineffective...)
+	return append(oo, r.External...)
+}
+
+// InspectAll inspects every owner and merges reports. If there are more than
+// one owner, repositories are referred as "owner/name" and problems of the
+// same user or email across owners are merged.
+func InspectAll(ctx context.Context, cli *github.Client, owners []string, opt InspectOptions) (*Report, error) {
+	if len(owners) == 1 {
+		return Inspect(ctx, cli, owners[0], opt)
+	}
+	reports := make([]*Report, len(owners))
+	for i, owner := range owners {
+		r, err := Inspect(ctx, cli, owner, opt)
+		if err != nil {
+			return nil, err
+		}
+		reports[i] = r
+	}
+	return mergeReports(owners, reports), nil
+}
+
+// mergeReports merges reports of owners in the same order.
+func mergeReports(owners []string, reports []*Report) *Report {
+	merged := &Report{}
+	for i, r := range reports {
+		qualify := func(repo string) string {
+			return owners[i] + "/" + repo
+		}
+		for _, o := range r.codeowners() {
+			for j, repo := range o.OwnRepos {
+				o.OwnRepos[j] = qualify(repo)
+			}
+			for _, oc := range o.Occurrences {
+				oc.Repo = qualify(oc.Repo)
+			}
+		}
+		for _, t := range r.WeakTeams {
+			for j, repo := range t.OwnRepos {
+				t.OwnRepos[j] = qualify(repo)
+			}
+		}
+		for _, o := range r.Stale {
+			o.Repo = qualify(o.Repo)
+		}
+
+		merged.Owners = mergeCodeowners(merged.Owners, r.Owners)
+		merged.Ineffective = mergeCodeowners(merged.Ineffective, r.Ineffective)
+		merged.External = mergeCodeowners(merged.External, r.External)
+		merged.Shadowed = append(merged.Shadowed, r.Shadowed...)
+		merged.WeakTeams = append(merged.WeakTeams, r.WeakTeams...)
+		merged.Stale = append(merged.Stale, r.Stale...)
+		merged.Repos = append(merged.Repos, r.Repos...)
+	}
+	sort.SliceStable(merged.Stale, func(i, j int) bool {
+		if merged.Stale[i].Name != merged.Stale[j].Name {
+			return merged.Stale[i].Name < merged.Stale[j].Name
+		}
+		return merged.Stale[i].Repo < merged.Stale[j].Repo
+	})
+	return merged
+}
+
+// mergeCodeowners appends oo to merged in order of name. Owners of the same
+// name are merged into one.
+func mergeCodeowners(merged, oo []*Codeowner) []*Codeowner {
+	byName := make(map[string]*Codeowner, len(merged))
+	for _, o := range merged {
+		byName[strings.ToLower(o.Name)] = o
+	}
+	for _, o := range oo {
+		m, ok := byName[strings.ToLower(o.Name)]
+		if !ok {
+			merged = append(merged, o)
+			byName[strings.ToLower(o.Name)] = o
+			continue
+		}
+		m.OwnRepos = append(m.OwnRepos, o.OwnRepos...)
+		m.Occurrences = append(m.Occurrences, o.Occurrences...)
+		if m.Successor == "" {
+			m.Successor = o.Successor
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Name < merged[j].Name
+	})
+	return merged
+}
+
+// splitExternal splits names into teams of other organizations than the owner
+// and the others.
+func splitExternal(owner string, names []string) (internal, external []string) {
+	internal = make([]string, 0, len(names))
+	for _, n := range names {
+		org, _, _ := strings.Cut(n, "/")
+		if ownerKind(n) == TeamOwner && !strings.EqualFold(org, owner) {
+			external = append(external, n)
+			continue
+		}
+		internal = append(internal, n)
+	}
+	return internal, external
+}
+
+// listExistingUsers returns names of user owners which exist.
+func listExistingUsers(ctx context.Context, cli *github.Client, names []string) ([]string, error) {
+	users := make([]string, 0)
+	for _, n := range names {
+		if ownerKind(n) != UserOwner {
+			continue
+		}
+		_, err := GetUser(ctx, cli, n)
+		if errors.Cause(err) == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, n)
+		}
+		users = append(users, n)
+	}
+	return users, nil
+}
+
+// resolveCommits resolves commits of repositories in the report to make
+// permalinks.
+func resolveCommits(ctx context.Context, cli *github.Client, all []*RepoCodeowners, report *Report, pool PoolOptions) error {
+	var names []string
+	for _, o := range report.codeowners() {
+		names = append(names, o.OwnRepos...)
+	}
+	for _, t := range report.WeakTeams {
+		names = append(names, t.OwnRepos...)
+	}
+	for _, o := range report.Stale {
+		names = append(names, o.Repo)
+	}
+	in := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		in[n] = struct{}{}
+	}
+	var rr []*RepoCodeowners
+	for _, rc := range all {
+		if _, ok := in[rc.Repo.GetName()]; ok {
+			rr = append(rr, rc)
+		}
+	}
+
+	return runPool(ctx, len(rr), pool, func(ctx context.Context, i int) (string, error) {
+		sha, err := GetCommitSHA(ctx, cli, rr[i].Repo, rr[i].Ref)
+		if err != nil {
+			return "", errors.Wrap(err, rr[i].Repo.GetFullName())
+		}
+		return sha, nil
+	}, func(i int, sha string) {
+		rr[i].SHA = sha
+	})
+}
+
+// listOccurrences returns rules having the owner in the repositories, in
+// order of repositories and lines.
+func listOccurrences(all []*RepoCodeowners, name string, repos []string, webURL string) []*Occurrence {
+	in := make(map[string]string, len(repos))
+	for _, r := range repos {
+		in[strings.ToLower(r)] = r
+	}
+	oo := make([]*Occurrence, 0)
+	for _, rc := range all {
+		repo, ok := in[strings.ToLower(rc.Repo.GetName())]
+		if !ok {
+			// repositories of many owners are referred by full name
+			repo, ok = in[strings.ToLower(rc.Repo.GetOwner().GetLogin()+"/"+rc.Repo.GetName())]
+			if !ok {
+				continue
+			}
+		}
+		commit := rc.SHA
+		if commit == "" {
+			commit = rc.Ref
+		}
+		for _, r := range rc.File.Rules() {
+			for _, o := range r.Owners {
+				if o.Kind() == UnknownOwner || !strings.EqualFold(o.Name(), name) {
+					continue
+				}
+				oo = append(oo, &Occurrence{
+					Repo:      repo,
+					Path:      rc.Path,
+					Ref:       rc.Ref,
+					Line:      r.Line,
+					Pattern:   r.Pattern,
+					Permalink: fmt.Sprintf("%s/%s/%s/blob/%s/%s#L%d", webURL, rc.Repo.GetOwner().GetLogin(), rc.Repo.GetName(), commit, rc.Path, r.Line),
+				})
+				break
+			}
+		}
+	}
+	sort.SliceStable(oo, func(i, j int) bool {
+		return oo[i].Repo < oo[j].Repo
+	})
+	return oo
+}
+
+// listWeakTeams returns team owners which are empty, have only one active
+// member, or have no active member. Member is active if it's a member of the
+// organization and not suspended.
+func listWeakTeams(ctx context.Context, cli *github.Client, teamOwners []*Codeowner, users []string, tree *TeamTree, opt InspectOptions) ([]*WeakTeam, error) {
+	members, err := listTeamMembers(ctx, cli, tree, teamOwners, opt)
 	if err != nil {
 		return nil, err
 	}
+	pool := opt.Pool
 
-	teams, err := listTeamNames(ctx, cli, owner)
+	orgMembers := make(map[string]struct{}, len(users))
+	for _, u := range users {
+		orgMembers[strings.ToLower(u)] = struct{}{}
+	}
+	// check suspension of each member once even if it belongs to many teams
+	var candidates []string
+	for _, logins := range members {
+		for _, l := range logins {
+			if _, ok := orgMembers[strings.ToLower(l)]; ok {
+				candidates = append(candidates, strings.ToLower(l))
+			}
+		}
+	}
+	candidates = set(candidates)
+	active := make(map[string]struct{}, len(candidates))
+	err = runPool(ctx, len(candidates), pool, func(ctx context.Context, i int) (bool, error) {
+		suspended, err := IsUserSuspended(ctx, cli, candidates[i])
+		if errors.Cause(err) == ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, candidates[i])
+		}
+		return !suspended, nil
+	}, func(i int, ok bool) {
+		if ok {
+			active[candidates[i]] = struct{}{}
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	ownerMapByName, err := listAllCodeowners(ctx, cli, owner)
+	var weak []*WeakTeam
+	for i, o := range teamOwners {
+		n := 0
+		for _, l := range members[i] {
+			if _, ok := active[strings.ToLower(l)]; ok {
+				n++
+			}
+		}
+		var issue TeamIssue
+		switch {
+		case len(members[i]) == 0:
+			issue = EmptyTeam
+		case n == 0:
+			issue = InactiveTeam
+		case n == 1:
+			issue = SingleMemberTeam
+		default:
+			continue
+		}
+		w := &WeakTeam{
+			Name:     o.Name,
+			Issue:    issue,
+			Members:  members[i],
+			OwnRepos: o.OwnRepos,
+		}
+		if p := tree.Parent(strings.SplitN(o.Name, "/", 2)[1]); p != "" {
+			w.Parent = tree.org + "/" + p
+		}
+		weak = append(weak, w)
+	}
+	return weak, nil
+}
+
+// listTeamMembers returns logins of members of each team owner. GitHub
+// includes members of child teams, which are excluded if opt.DirectMembers.
+// A member of both the team and its child team is regarded as a member of the
+// child team only then.
+func listTeamMembers(ctx context.Context, cli *github.Client, tree *TeamTree, teamOwners []*Codeowner, opt InspectOptions) ([][]string, error) {
+	var slugs []string
+	for _, o := range teamOwners {
+		slug := strings.SplitN(o.Name, "/", 2)[1]
+		slugs = append(slugs, slug)
+		if opt.DirectMembers {
+			slugs = append(slugs, tree.Children(slug)...)
+		}
+	}
+	slugs = set(slugs)
+
+	bySlug := make(map[string][]string, len(slugs))
+	err := runPool(ctx, len(slugs), opt.Pool, func(ctx context.Context, i int) ([]string, error) {
+		uu, err := ListTeamMembers(ctx, cli, tree.org, slugs[i])
+		if err != nil {
+			return nil, errors.Wrap(err, tree.org+"/"+slugs[i])
+		}
+		logins := make([]string, len(uu))
+		for j, u := range uu {
+			logins[j] = u.GetLogin()
+		}
+		return logins, nil
+	}, func(i int, logins []string) {
+		bySlug[strings.ToLower(slugs[i])] = logins
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	members := make([][]string, len(teamOwners))
+	for i, o := range teamOwners {
+		slug := strings.SplitN(o.Name, "/", 2)[1]
+		all := bySlug[strings.ToLower(slug)]
+		if !opt.DirectMembers {
+			members[i] = all
+			continue
+		}
+		var nested []string
+		for _, c := range tree.Children(slug) {
+			nested = append(nested, bySlug[strings.ToLower(c)]...)
+		}
+		members[i] = diff(all, nested)
+	}
+	return members, nil
+}
+
+// listIneffectiveOwners returns owners lacking write access to repositories
+// they own, in order of name. Missing owners are not checked.
+func listIneffectiveOwners(ctx context.Context, cli *github.Client, all []*RepoCodeowners, missing []string, pool PoolOptions) ([]*Codeowner, error) {
+	skip := make(map[string]struct{}, len(missing))
+	for _, n := range missing {
+		skip[strings.ToLower(n)] = struct{}{}
+	}
+
+	byRepo := make(map[string][]string)
+	err := runPool(ctx, len(all), pool, func(ctx context.Context, i int) ([]string, error) {
+		rc := all[i]
+		var names []string
+		for _, n := range rc.File.OwnerNames() {
+			if _, ok := skip[strings.ToLower(n)]; ok {
+				continue
+			}
+			ok, err := hasWriteAccess(ctx, cli, rc.Repo, n)
+			if err != nil {
+				return nil, errors.Wrap(err, rc.Repo.GetFullName())
+			}
+			if !ok {
+				names = append(names, n)
+			}
+		}
+		return names, nil
+	}, func(i int, names []string) {
+		if len(names) > 0 {
+			byRepo[all[i].Repo.GetName()] = names
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ownerMapByName := groupByCodeowner(byRepo)
 	names := make([]string, 0, len(ownerMapByName))
 	for k := range ownerMapByName {
 		names = append(names, k)
 	}
 	sort.Strings(names)
+	ineffective := make([]*Codeowner, len(names))
+	for i, n := range names {
+		ineffective[i] = ownerMapByName[n]
+	}
+	return ineffective, nil
+}
 
-	diffNames := diff(names, append(users, teams...))
-
-	owners := make([]*Codeowner, len(diffNames))
-	for i, n := range diffNames {
-		owners[i] = ownerMapByName[n]
+// hasWriteAccess reports whether the owner has write access to the
+// repository. Email owner is regarded as effective since it's not always
+// resolved to a user.
+func hasWriteAccess(ctx context.Context, cli *github.Client, r *github.Repository, name string) (bool, error) {
+	switch ownerKind(name) {
+	case TeamOwner:
+		org, slug, _ := strings.Cut(name, "/")
+		return HasTeamWriteAccess(ctx, cli, r, org, slug)
+	case UserOwner:
+		return HasUserWriteAccess(ctx, cli, r, name)
 	}
-	return owners, nil
+	return true, nil
 }
 
 func listMemberNames(ctx context.Context, cli *github.Client, owner string) ([]string, error) {
@@ -56,74 +663,132 @@ func listMemberNames(ctx context.Context, cli *github.Client, owner string) ([]s
 	return names, nil
 }
 
-func listTeamNames(ctx context.Context, cli *github.Client, owner string) ([]string, error) {
-	teams, err := ListTeams(ctx, cli, owner)
-	if err != nil {
-		return nil, err
+// listMemberEmails returns emails which belong to one of users, or to any
+// user if users is nil. Email is resolved to the user only if it is the
+// public email of the profile, since user search doesn't match others.
+func listMemberEmails(ctx context.Context, cli *github.Client, emails, users []string) ([]string, error) {
+	m := make(map[string]struct{}, len(users))
+	for _, u := range users {
+		m[strings.ToLower(u)] = struct{}{}
 	}
-	names := make([]string, len(teams))
-	for i, team := range teams {
-		names[i] = owner + "/" + team.GetSlug()
+
+	memberEmails := make([]string, 0, len(emails))
+	for _, e := range emails {
+		user, err := FindUserByEmail(ctx, cli, e)
+		if errors.Cause(err) == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m[strings.ToLower(user.GetLogin())]; ok || users == nil {
+			memberEmails = append(memberEmails, e)
+		}
 	}
-	return names, nil
+	return memberEmails, nil
 }
 
-func listAllCodeowners(ctx context.Context, cli *github.Client, owner string) (map[string]*Codeowner, error) {
-	rr, err := ListActivatedRepositories(ctx, cli, owner)
+// listAllCodeowners returns CODEOWNERS files of every activated repository
+// having one, in order of repositories.
+func listAllCodeowners(ctx context.Context, cli *github.Client, owner string, opt InspectOptions) ([]*RepoCodeowners, error) {
+	rr, err := ListActivatedRepositories(ctx, cli, owner, opt.Repos)
 	if err != nil {
 		return nil, err
 	}
 
-	ownersByRepo := make(map[string][]string, len(rr))
-	for _, r := range rr {
-		content, err := GetCodeownersContent(ctx, cli, r)
+	all := make([]*RepoCodeowners, 0, len(rr))
+	err = runPool(ctx, len(rr), opt.Pool, func(ctx context.Context, i int) (*RepoCodeowners, error) {
+		rc, err := getRepoCodeowners(ctx, cli, rr[i], opt.Branch, opt.Shadowed)
 		if errors.Cause(err) == ErrNotFound {
-			continue
+			return nil, nil
 		}
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, rr[i].GetFullName())
+		}
+		return rc, nil
+	}, func(i int, rc *RepoCodeowners) {
+		if rc != nil {
+			all = append(all, rc)
 		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
 
-		s, err := content.GetContent()
+// getRepoCodeowners returns the effective CODEOWNERS file of the repository
+// in the branch if it exists. Ignored ones are looked up only if shadowed is
+// true.
+func getRepoCodeowners(ctx context.Context, cli *github.Client, r *github.Repository, branch string, shadowed bool) (*RepoCodeowners, error) {
+	ref, err := codeownersRef(ctx, cli, r, branch)
+	if err != nil {
+		return nil, err
+	}
+	var contents []*github.RepositoryContent
+	if shadowed {
+		cc, err := ListCodeownersContentsAt(ctx, cli, r, ref)
 		if err != nil {
 			return nil, err
 		}
+		contents = cc
+	} else {
+		content, err := GetCodeownersContentAt(ctx, cli, r, ref)
+		if err != nil {
+			return nil, err
+		}
+		contents = []*github.RepositoryContent{content}
+	}
 
-		ownersByRepo[r.GetName()] = parseCodeowners(s)
+	s, err := contents[0].GetContent()
+	if err != nil {
+		return nil, err
+	}
+	rc := &RepoCodeowners{
+		Repo: r,
+		Path: contents[0].GetPath(),
+		Ref:  r.GetDefaultBranch(),
+		File: Parse(s),
+	}
+	if ref != nil {
+		rc.Ref = branch
+	}
+	for _, c := range contents[1:] {
+		rc.Shadowed = append(rc.Shadowed, c.GetPath())
 	}
+	return rc, nil
+}
 
-	return groupByCodeowner(ownersByRepo), nil
+// ownersByRepo returns owner names of each repository.
+func ownersByRepo(all []*RepoCodeowners) map[string][]string {
+	m := make(map[string][]string, len(all))
+	for _, rc := range all {
+		m[rc.Repo.GetName()] = rc.File.OwnerNames()
+	}
+	return m
 }
 
 func parseCodeowners(s string) []string {
-	ss := strings.Split(s, sep)
-	nn := make([]string, 0)
-	for _, l := range ss {
-		if strings.HasPrefix(l, commentPrefix) {
-			continue
-		}
-		if !strings.Contains(l, mentionPrefix) {
-			continue
-		}
-		cc := strings.Split(l, mentionPrefix)
-		for _, name := range cc[1:] {
-			// identifier
-			n := strings.TrimSpace(name)
-			nn = append(nn, n)
-		}
-	}
-	return set(nn)
+	return Parse(s).OwnerNames()
 }
 
 func groupByCodeowner(ownersByRepo map[string][]string) map[string]*Codeowner {
+	repos := make([]string, 0, len(ownersByRepo))
+	for k := range ownersByRepo {
+		repos = append(repos, k)
+	}
+	// keep repositories of owner in order
+	sort.Strings(repos)
+
 	ownerMap := make(map[string]*Codeowner)
-	for k, vv := range ownersByRepo {
-		for _, v := range vv {
+	for _, k := range repos {
+		for _, v := range ownersByRepo[k] {
 			if o, ok := ownerMap[v]; ok {
 				o.OwnRepos = append(o.OwnRepos, k)
 			} else {
 				ownerMap[v] = &Codeowner{
 					Name:     v,
+					Kind:     ownerKind(v),
 					OwnRepos: []string{k},
 				}
 			}
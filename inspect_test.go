@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/go-github/v48/github"
@@ -53,6 +54,10 @@ func Test_listAllCodeowners(t *testing.T) {
 					rw.WriteHeader(http.StatusNotFound)
 					return
 				}
+				if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/api/v3/repos/%s/%s/contents/%s", mockOwner, mockRepo, "docs/CODEOWNERS") {
+					rw.WriteHeader(http.StatusNotFound)
+					return
+				}
 				t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
 			},
 			expected: map[string]*Codeowner{},
@@ -152,7 +157,8 @@ func Test_listAllCodeowners(t *testing.T) {
 			require.NoError(t, err)
 
 			ctx := context.Background()
-			got, err := listAllCodeowners(ctx, mockGithubCli, mockOwner)
+			all, err := listAllCodeowners(ctx, mockGithubCli, mockOwner, InspectOptions{Branch: prBranch, Pool: PoolOptions{Concurrency: 2}})
+			got := groupByCodeowner(ownersByRepo(all))
 
 			assert.NoError(t, err)
 			for k, v := range tc.expected {
@@ -163,6 +169,95 @@ func Test_listAllCodeowners(t *testing.T) {
 	}
 }
 
+func Test_getRepoCodeowners(t *testing.T) {
+	const (
+		mockOwner = "some-org"
+		mockRepo  = "some-repo"
+	)
+
+	cases := []struct {
+		name             string
+		existing         []string
+		shadowed         bool
+		expectedPath     string
+		expectedShadowed []string
+	}{
+		{
+			name:         "docs only",
+			existing:     []string{"docs/CODEOWNERS"},
+			shadowed:     true,
+			expectedPath: "docs/CODEOWNERS",
+		},
+		{
+			name:             "github takes precedence",
+			existing:         []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"},
+			shadowed:         true,
+			expectedPath:     ".github/CODEOWNERS",
+			expectedShadowed: []string{"CODEOWNERS", "docs/CODEOWNERS"},
+		},
+		{
+			name:             "root takes precedence over docs",
+			existing:         []string{"CODEOWNERS", "docs/CODEOWNERS"},
+			shadowed:         true,
+			expectedPath:     "CODEOWNERS",
+			expectedShadowed: []string{"docs/CODEOWNERS"},
+		},
+		{
+			name:         "ignore shadowed",
+			existing:     []string{".github/CODEOWNERS", "CODEOWNERS"},
+			shadowed:     false,
+			expectedPath: ".github/CODEOWNERS",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			existing := make(map[string]bool)
+			for _, p := range tc.existing {
+				existing[p] = true
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/api/v3/repos/%s/%s/branches/%s", mockOwner, mockRepo, prBranch) {
+					rw.WriteHeader(http.StatusNotFound)
+					return
+				}
+				prefix := fmt.Sprintf("/api/v3/repos/%s/%s/contents/", mockOwner, mockRepo)
+				if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, prefix) {
+					p := strings.TrimPrefix(r.URL.Path, prefix)
+					if !existing[p] {
+						rw.WriteHeader(http.StatusNotFound)
+						return
+					}
+					if !tc.shadowed && p != tc.expectedPath {
+						t.Errorf("should not look up %s", p)
+					}
+					rw.Header().Set("Content-Type", "application/json")
+					_, err := io.WriteString(rw, fmt.Sprintf(`{"path": "%s", "content": "* @a"}`, p))
+					require.NoError(t, err)
+					return
+				}
+				t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			}))
+			defer server.Close()
+
+			mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+			require.NoError(t, err)
+			repo := &github.Repository{
+				Owner:         &github.User{Login: github.String(mockOwner)},
+				Name:          github.String(mockRepo),
+				DefaultBranch: github.String("main"),
+			}
+
+			got, err := getRepoCodeowners(context.Background(), mockGithubCli, repo, prBranch, tc.shadowed)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, got.Path)
+			assert.Equal(t, tc.expectedShadowed, got.Shadowed)
+			assert.Equal(t, []string{"a"}, got.File.OwnerNames())
+		})
+	}
+}
+
 func Test_parseCodeowners(t *testing.T) {
 
 	cases := []struct {
@@ -195,6 +290,11 @@ func Test_parseCodeowners(t *testing.T) {
 			given:    "* @a\n.github @a",
 			expected: []string{"a"},
 		},
+		{
+			name:     "email",
+			given:    "* user@example.com @a",
+			expected: []string{"user@example.com", "a"},
+		},
 	}
 	for _, tc := range cases {
 		tc := tc
@@ -206,6 +306,288 @@ func Test_parseCodeowners(t *testing.T) {
 	}
 }
 
+func Test_listMemberEmails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v3/search/users" {
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("q") {
+		case "member@example.com in:email":
+			_, err := io.WriteString(rw, `{"total_count": 1, "items": [{"login": "Member"}]}`)
+			require.NoError(t, err)
+		case "outsider@example.com in:email":
+			_, err := io.WriteString(rw, `{"total_count": 1, "items": [{"login": "outsider"}]}`)
+			require.NoError(t, err)
+		default:
+			_, err := io.WriteString(rw, `{"total_count": 0, "items": []}`)
+			require.NoError(t, err)
+		}
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+
+	got, err := listMemberEmails(context.Background(), mockGithubCli, []string{
+		"member@example.com",
+		"outsider@example.com",
+		"unknown@example.com",
+	}, []string{"member"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"member@example.com"}, got)
+}
+
+func Test_listIneffectiveOwners(t *testing.T) {
+	const mockOwner = "some-org"
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/orgs/some-org/teams/team/repos/some-org/repo1":
+			_, err := io.WriteString(rw, `{"permissions": {"pull": true, "push": true}}`)
+			require.NoError(t, err)
+		case "/api/v3/orgs/some-org/teams/team/repos/some-org/repo2":
+			_, err := io.WriteString(rw, `{"permissions": {"pull": true}}`)
+			require.NoError(t, err)
+		case "/api/v3/orgs/some-org/teams/outside/repos/some-org/repo2":
+			rw.WriteHeader(http.StatusNotFound)
+		case "/api/v3/repos/some-org/repo1/collaborators/a/permission":
+			_, err := io.WriteString(rw, `{"permission": "write"}`)
+			require.NoError(t, err)
+		case "/api/v3/repos/some-org/repo2/collaborators/a/permission":
+			_, err := io.WriteString(rw, `{"permission": "read"}`)
+			require.NoError(t, err)
+		default:
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+		}
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+	newRepo := func(name string) *github.Repository {
+		return &github.Repository{
+			Owner: &github.User{Login: github.String(mockOwner)},
+			Name:  github.String(name),
+		}
+	}
+	all := []*RepoCodeowners{
+		{Repo: newRepo("repo1"), File: Parse("* @a @some-org/team @missing")},
+		{Repo: newRepo("repo2"), File: Parse("* @a @some-org/team @some-org/outside a@example.com")},
+	}
+
+	got, err := listIneffectiveOwners(context.Background(), mockGithubCli, all, []string{"Missing"}, PoolOptions{Concurrency: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*Codeowner{
+		{Name: "a", Kind: UserOwner, OwnRepos: []string{"repo2"}},
+		{Name: "some-org/outside", Kind: TeamOwner, OwnRepos: []string{"repo2"}},
+		{Name: "some-org/team", Kind: TeamOwner, OwnRepos: []string{"repo2"}},
+	}, got)
+}
+
+func Test_listWeakTeams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		var body string
+		switch r.URL.Path {
+		case "/api/v3/orgs/org/teams/empty/members":
+			body = `[]`
+		case "/api/v3/orgs/org/teams/single/members":
+			body = `[{"login": "a"}]`
+		case "/api/v3/orgs/org/teams/inactive/members":
+			body = `[{"login": "suspended"}, {"login": "left"}]`
+		case "/api/v3/orgs/org/teams/healthy/members":
+			body = `[{"login": "A"}, {"login": "b"}, {"login": "suspended"}]`
+		case "/api/v3/orgs/org/teams/parent/members":
+			body = `[{"login": "a"}, {"login": "b"}]`
+		case "/api/v3/orgs/org/teams/child/members":
+			body = `[{"login": "b"}]`
+		case "/api/v3/users/a", "/api/v3/users/b":
+			body = `{"login": "a"}`
+		case "/api/v3/users/suspended":
+			body = `{"login": "suspended", "suspended_at": "2022-01-01T00:00:00Z"}`
+		default:
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			return
+		}
+		_, err := io.WriteString(rw, body)
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+	newTeam := func(slug, parent string) *github.Team {
+		team := &github.Team{Slug: github.String(slug)}
+		if parent != "" {
+			team.Parent = &github.Team{Slug: github.String(parent)}
+		}
+		return team
+	}
+	tree := NewTeamTree("org", []*github.Team{
+		newTeam("empty", ""),
+		newTeam("single", ""),
+		newTeam("inactive", ""),
+		newTeam("healthy", ""),
+		newTeam("parent", ""),
+		newTeam("child", "parent"),
+	})
+	teamOwners := []*Codeowner{
+		{Name: "org/empty", Kind: TeamOwner, OwnRepos: []string{"repo1"}},
+		{Name: "org/single", Kind: TeamOwner, OwnRepos: []string{"repo1", "repo2"}},
+		{Name: "org/inactive", Kind: TeamOwner, OwnRepos: []string{"repo2"}},
+		{Name: "org/healthy", Kind: TeamOwner, OwnRepos: []string{"repo3"}},
+		{Name: "org/parent", Kind: TeamOwner, OwnRepos: []string{"repo3"}},
+		{Name: "org/child", Kind: TeamOwner, OwnRepos: []string{"repo4"}},
+	}
+	users := []string{"a", "b", "suspended"}
+
+	t.Run("direct members", func(t *testing.T) {
+		got, err := listWeakTeams(context.Background(), mockGithubCli, teamOwners, users, tree, InspectOptions{DirectMembers: true, Pool: PoolOptions{Concurrency: 2}})
+
+		require.NoError(t, err)
+		assert.Equal(t, []*WeakTeam{
+			{Name: "org/empty", Issue: EmptyTeam, Members: []string{}, OwnRepos: []string{"repo1"}},
+			{Name: "org/single", Issue: SingleMemberTeam, Members: []string{"a"}, OwnRepos: []string{"repo1", "repo2"}},
+			{Name: "org/inactive", Issue: InactiveTeam, Members: []string{"suspended", "left"}, OwnRepos: []string{"repo2"}},
+			{Name: "org/parent", Issue: SingleMemberTeam, Members: []string{"a"}, OwnRepos: []string{"repo3"}},
+			{Name: "org/child", Issue: SingleMemberTeam, Parent: "org/parent", Members: []string{"b"}, OwnRepos: []string{"repo4"}},
+		}, got)
+	})
+
+	t.Run("nested members", func(t *testing.T) {
+		got, err := listWeakTeams(context.Background(), mockGithubCli, teamOwners[3:5], users, tree, InspectOptions{})
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
+
+func Test_resolveCommits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/org/repo1/commits/main":
+			_, err := io.WriteString(rw, "abc")
+			require.NoError(t, err)
+		default:
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+		}
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+	newRepo := func(name string) *github.Repository {
+		return &github.Repository{
+			Owner: &github.User{Login: github.String("org")},
+			Name:  github.String(name),
+		}
+	}
+	all := []*RepoCodeowners{
+		{Repo: newRepo("repo1"), Ref: "main", File: Parse("* @gone")},
+		{Repo: newRepo("repo2"), Ref: "main", File: Parse("* @a")},
+	}
+	report := &Report{Owners: []*Codeowner{{Name: "gone", Kind: UserOwner, OwnRepos: []string{"repo1"}}}}
+
+	err = resolveCommits(context.Background(), mockGithubCli, all, report, PoolOptions{Concurrency: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, "abc", all[0].SHA)
+	assert.Equal(t, "", all[1].SHA)
+}
+
+func Test_listOccurrences(t *testing.T) {
+	newRepo := func(name string) *github.Repository {
+		return &github.Repository{
+			Owner: &github.User{Login: github.String("org")},
+			Name:  github.String(name),
+		}
+	}
+	all := []*RepoCodeowners{
+		{Repo: newRepo("repo2"), Path: "CODEOWNERS", Ref: "update-codeowners", File: Parse("# @gone\n*.go @gone @GONE\n")},
+		{Repo: newRepo("repo1"), Path: ".github/CODEOWNERS", Ref: "main", SHA: "abc", File: Parse("* @a\n/docs/ @Gone\n/api/ @gone\n")},
+		{Repo: newRepo("repo3"), Path: "CODEOWNERS", Ref: "main", File: Parse("* @gone\n")},
+	}
+
+	got := listOccurrences(all, "gone", []string{"repo1", "repo2"}, "https://github.com")
+
+	assert.Equal(t, []*Occurrence{
+		{Repo: "repo1", Path: ".github/CODEOWNERS", Ref: "main", Line: 2, Pattern: "/docs/", Permalink: "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L2"},
+		{Repo: "repo1", Path: ".github/CODEOWNERS", Ref: "main", Line: 3, Pattern: "/api/", Permalink: "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L3"},
+		{Repo: "repo2", Path: "CODEOWNERS", Ref: "update-codeowners", Line: 2, Pattern: "*.go", Permalink: "https://github.com/org/repo2/blob/update-codeowners/CODEOWNERS#L2"},
+	}, got)
+}
+
+func Test_splitExternal(t *testing.T) {
+	internal, external := splitExternal("Org", []string{"a", "a@example.com", "org/team", "other/team"})
+
+	assert.Equal(t, []string{"a", "a@example.com", "org/team"}, internal)
+	assert.Equal(t, []string{"other/team"}, external)
+}
+
+func Test_listExistingUsers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/users/a":
+			_, err := io.WriteString(rw, `{"login": "a", "type": "User"}`)
+			require.NoError(t, err)
+		case "/api/v3/users/gone":
+			rw.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+		}
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+
+	got, err := listExistingUsers(context.Background(), mockGithubCli, []string{"a", "a@example.com", "gone", "org/team"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, got)
+}
+
+func Test_mergeReports(t *testing.T) {
+	reports := []*Report{
+		{
+			Owners: []*Codeowner{
+				{Name: "gone", Kind: UserOwner, OwnRepos: []string{"repo1"}, Occurrences: []*Occurrence{{Repo: "repo1", Line: 1}}},
+				{Name: "org1/team", Kind: TeamOwner, OwnRepos: []string{"repo1"}},
+			},
+			External: []*Codeowner{
+				{Name: "org2/team", Kind: TeamOwner, OwnRepos: []string{"repo1"}},
+			},
+			Stale: []*StaleOwner{{Name: "b", Repo: "repo1"}},
+		},
+		{
+			Owners: []*Codeowner{
+				{Name: "Gone", Kind: UserOwner, OwnRepos: []string{"repo1"}, Occurrences: []*Occurrence{{Repo: "repo1", Line: 2}}},
+			},
+			WeakTeams: []*WeakTeam{{Name: "org2/solo", Issue: SingleMemberTeam, OwnRepos: []string{"repo2"}}},
+			Stale:     []*StaleOwner{{Name: "a", Repo: "repo1"}},
+		},
+	}
+
+	got := mergeReports([]string{"org1", "user"}, reports)
+
+	assert.Equal(t, []*Codeowner{
+		{
+			Name: "gone", Kind: UserOwner, OwnRepos: []string{"org1/repo1", "user/repo1"},
+			Occurrences: []*Occurrence{{Repo: "org1/repo1", Line: 1}, {Repo: "user/repo1", Line: 2}},
+		},
+		{Name: "org1/team", Kind: TeamOwner, OwnRepos: []string{"org1/repo1"}},
+	}, got.Owners)
+	assert.Equal(t, []*Codeowner{{Name: "org2/team", Kind: TeamOwner, OwnRepos: []string{"org1/repo1"}}}, got.External)
+	assert.Equal(t, []*WeakTeam{{Name: "org2/solo", Issue: SingleMemberTeam, OwnRepos: []string{"user/repo2"}}}, got.WeakTeams)
+	assert.Equal(t, []*StaleOwner{{Name: "a", Repo: "user/repo1"}, {Name: "b", Repo: "org1/repo1"}}, got.Stale)
+}
+
 func Test_groupByCodeowner(t *testing.T) {
 	t.Run("merge", func(t *testing.T) {
 		m := map[string][]string{
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/pkg/errors"
+)
+
+// ProblemKind is a kind of rule having no effect.
+type ProblemKind int
+
+const (
+	// DeadRule matches no file.
+	DeadRule ProblemKind = iota + 1
+	// ShadowedRule is overridden by later rules for every matched file.
+	ShadowedRule
+)
+
+func (k ProblemKind) String() string {
+	switch k {
+	case DeadRule:
+		return "dead"
+	case ShadowedRule:
+		return "shadowed"
+	}
+	return "unknown"
+}
+
+// Problem is a rule having no effect on the repository.
+type Problem struct {
+	Rule *Rule
+	Kind ProblemKind
+	// ShadowedBy are lines of the later rules which effectively own files
+	// matched by the shadowed rule.
+	ShadowedBy []int
+}
+
+// Message describes why the rule has no effect.
+func (p *Problem) Message() string {
+	switch p.Kind {
+	case DeadRule:
+		return fmt.Sprintf("%s rule %q matches no file", p.Kind, p.Rule.Pattern)
+	case ShadowedRule:
+		return fmt.Sprintf("%s rule %q is overridden by line %s for every matched file", p.Kind, p.Rule.Pattern, joinLines(p.ShadowedBy))
+	}
+	return fmt.Sprintf("%s rule %q", p.Kind, p.Rule.Pattern)
+}
+
+// Fix suggests how to fix the problem.
+func (p *Problem) Fix() string {
+	if p.Kind == ShadowedRule {
+		return fmt.Sprintf("remove the line, or move it after line %d to take effect", p.ShadowedBy[len(p.ShadowedBy)-1])
+	}
+	return "remove the line"
+}
+
+func joinLines(lines []int) string {
+	ss := make([]string, len(lines))
+	for i, l := range lines {
+		ss[i] = strconv.Itoa(l)
+	}
+	return strings.Join(ss, ", ")
+}
+
+// Lint finds rules matching no file and rules whose every matched file is
+// owned by later rules, in order of line.
+func Lint(f *File, files []string) ([]*Problem, error) {
+	m, err := NewMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]int, len(m.rules))
+	effective := make([]int, len(m.rules))
+	shadowedBy := make([]map[int]struct{}, len(m.rules))
+	for _, p := range files {
+		last := -1
+		var hits []int
+		for i, re := range m.patterns {
+			if re.MatchString(p) {
+				hits = append(hits, i)
+				last = i
+			}
+		}
+		for _, i := range hits {
+			matched[i]++
+			if i == last {
+				effective[i]++
+				continue
+			}
+			if shadowedBy[i] == nil {
+				shadowedBy[i] = make(map[int]struct{})
+			}
+			shadowedBy[i][m.rules[last].Line] = struct{}{}
+		}
+	}
+
+	var pp []*Problem
+	for i, r := range m.rules {
+		switch {
+		case matched[i] == 0:
+			pp = append(pp, &Problem{Rule: r, Kind: DeadRule})
+		case effective[i] == 0:
+			lines := make([]int, 0, len(shadowedBy[i]))
+			for l := range shadowedBy[i] {
+				lines = append(lines, l)
+			}
+			sort.Ints(lines)
+			pp = append(pp, &Problem{Rule: r, Kind: ShadowedRule, ShadowedBy: lines})
+		}
+	}
+	return pp, nil
+}
+
+// RepoLint is problems of the effective CODEOWNERS file of a repository.
+type RepoLint struct {
+	Repo     *github.Repository
+	Path     string
+	Problems []*Problem
+}
+
+// LintRepo lints the effective CODEOWNERS file against the repository tree at
+// ref. The default branch is used if ref is nil. It returns ErrNotFound if
+// the repository has no CODEOWNERS file.
+func LintRepo(ctx context.Context, cli *github.Client, r *github.Repository, ref *string) (*RepoLint, error) {
+	content, err := GetCodeownersContentAt(ctx, cli, r, ref)
+	if err != nil {
+		return nil, err
+	}
+	s, err := content.GetContent()
+	if err != nil {
+		return nil, errors.Wrap(err, "content.GetContent")
+	}
+	files, err := ListFiles(ctx, cli, r, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	pp, err := Lint(Parse(s), files)
+	if err != nil {
+		return nil, errors.Wrap(err, content.GetPath())
+	}
+	return &RepoLint{
+		Repo:     r,
+		Path:     content.GetPath(),
+		Problems: pp,
+	}, nil
+}
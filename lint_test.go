@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	files := []string{
+		"README.md",
+		"src/a.go",
+		"src/b.go",
+		"docs/a.md",
+	}
+
+	cases := []struct {
+		name     string
+		given    string
+		expected []*Problem
+	}{
+		{
+			name:  "no problem",
+			given: "* @a\n/src/ @b\n",
+		},
+		{
+			name:  "dead rule",
+			given: "* @a\n/legacy/ @b\n*.js @c\n",
+			expected: []*Problem{
+				{Rule: &Rule{Line: 2, Pattern: "/legacy/", Space: " ", Owners: []*Owner{{Token: "@b"}}}, Kind: DeadRule},
+				{Rule: &Rule{Line: 3, Pattern: "*.js", Space: " ", Owners: []*Owner{{Token: "@c"}}}, Kind: DeadRule},
+			},
+		},
+		{
+			name:  "shadowed rule",
+			given: "/src/a.go @a\n*.go @b\n*.md @c\n* @d\n/src/ @e\n",
+			expected: []*Problem{
+				{Rule: &Rule{Line: 1, Pattern: "/src/a.go", Space: " ", Owners: []*Owner{{Token: "@a"}}}, Kind: ShadowedRule, ShadowedBy: []int{5}},
+				{Rule: &Rule{Line: 2, Pattern: "*.go", Space: " ", Owners: []*Owner{{Token: "@b"}}}, Kind: ShadowedRule, ShadowedBy: []int{5}},
+				{Rule: &Rule{Line: 3, Pattern: "*.md", Space: " ", Owners: []*Owner{{Token: "@c"}}}, Kind: ShadowedRule, ShadowedBy: []int{4}},
+			},
+		},
+		{
+			name:  "shadowed by many rules",
+			given: "* @a\n/src/ @b\n*.md @c\n",
+			expected: []*Problem{
+				{Rule: &Rule{Line: 1, Pattern: "*", Space: " ", Owners: []*Owner{{Token: "@a"}}}, Kind: ShadowedRule, ShadowedBy: []int{2, 3}},
+			},
+		},
+		{
+			name:  "partially overridden",
+			given: "*.go @a\n/src/a.go @b\n",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Lint(Parse(tc.given), files)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestProblem_Message(t *testing.T) {
+	dead := &Problem{Rule: &Rule{Pattern: "/legacy/"}, Kind: DeadRule}
+	shadowed := &Problem{Rule: &Rule{Pattern: "*"}, Kind: ShadowedRule, ShadowedBy: []int{2, 3}}
+
+	assert.Equal(t, `dead rule "/legacy/" matches no file`, dead.Message())
+	assert.Equal(t, "remove the line", dead.Fix())
+	assert.Equal(t, `shadowed rule "*" is overridden by line 2, 3 for every matched file`, shadowed.Message())
+	assert.Equal(t, "remove the line, or move it after line 3 to take effect", shadowed.Fix())
+}
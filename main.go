@@ -2,7 +2,14 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/google/go-github/v48/github"
@@ -10,112 +17,1184 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error
+}
+
+func commands() []*command {
+	return []*command{
+		{
+			name:  "inspect",
+			args:  "<owner>...",
+			short: "Inspect codeowners should be removed in organizations or user accounts.",
+			run:   runInspect,
+		},
+		{
+			name:  "replace",
+			args:  "<owners> <old> [<new>] | --map <file> <owners>",
+			short: "Replace codeowners old to new one. Remove old if new is omitted.",
+			run:   runReplace,
+		},
+		{
+			name:  "who-owns",
+			args:  "<owner/repo> <path>...",
+			short: "Print effective codeowners and the matching line of each path.",
+			run:   runWhoOwns,
+		},
+		{
+			name:  "coverage",
+			args:  "<owners> | <owner/repo>",
+			short: "Report how many files and directories have codeowners.",
+			run:   runCoverage,
+		},
+		{
+			name:  "lint",
+			args:  "<owners> | <owner/repo>",
+			short: "Find codeowners rules matching no file or overridden by later rules.",
+			run:   runLint,
+		},
+		{
+			name:  "suggest",
+			args:  "<owner/repo>",
+			short: "Suggest codeowners of each rule by recent commits and reviews.",
+			run:   runSuggest,
+		},
+	}
+}
+
 func main() {
 	log.SetFormatter(&log.JSONFormatter{
 		DisableTimestamp: true,
 		PrettyPrint:      false,
 	})
 
-	if err := inspect(); err != nil {
-		log.WithError(err).Fatal("failed to inspect")
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			return run([]string{args[1], "-h"}, stdout, stderr)
+		}
+		printUsage(stdout)
+		return exitOK
 	}
-	if err := replace(); err != nil {
-		log.WithError(err).Fatal("failed to replace")
+
+	var cmd *command
+	for _, c := range commands() {
+		if c.name == name {
+			cmd = c
+			break
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "codeowners: unknown command %q\n\n", name)
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.String("config", "", "configuration `file` (default $"+configEnv+" or "+configFileName+" in working directory or its parents)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "%s\n\nUsage:\n  codeowners %s [flags] %s\n\nFlags:\n", cmd.short, cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := cmd.run(ctx, stdout, fs, args[1:])
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	var uerr *usageError
+	if errors.As(err, &uerr) {
+		// flag package already reports its own parse errors
+		if uerr.msg != "" {
+			fmt.Fprintf(stderr, "codeowners %s: %s\n\n", cmd.name, uerr.msg)
+			fs.Usage()
+		}
+		return exitUsage
 	}
+	log.WithError(err).Errorf("failed to %s", cmd.name)
+	return exitError
 }
 
-func inspect() error {
-	ctx := context.Background()
-	// TODO: Support enterprise github client
-	cli := NewGitHubClient(ctx, "")
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, "Usage:\n  codeowners <command> [flags] [args]\n\nCommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.short)
+	}
+	fmt.Fprint(w, "\nRun 'codeowners help <command>' for more information on a command.\n")
+}
 
-	owners, err := Inspect(ctx, cli, "org")
-	if err != nil {
-		return err
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func newUsageError(format string, a ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, a...)}
+}
+
+// parseArgs parses flags which may be interspersed with positional arguments
+// and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{}
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// everything after "--" is positional
+		if i := len(args) - len(rest) - 1; i >= 0 && args[i] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
-	for _, o := range owners {
-		log.WithField("owner", o.Name).WithField("repos", o.OwnRepos).Info("should be replaced")
+}
+
+// stringsFlag is a repeatable flag which also accepts comma separated values.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		*f = append(*f, s)
 	}
 	return nil
 }
 
-func replace() error {
-	ctx := context.Background()
-	// TODO: Support enterprise github client
-	cli := NewGitHubClient(ctx, "")
-	// TODO: Pass by commandline argument
-	repos, err := ListActivatedRepositories(ctx, cli, "org")
+// repeatedFlag is a flag which can be given multiple times. Unlike
+// stringsFlag, values are not split by comma.
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *repeatedFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// githubFlags are flags to connect GitHub shared by commands.
+type githubFlags struct {
+	url      string
+	caBundle string
+	token    string
+
+	appID             int64
+	appPrivateKey     string
+	appInstallationID int64
+
+	maxAttempts int
+}
+
+func (f *githubFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.url, "github-url", "", "API base `url` of GitHub Enterprise Server (default $GITHUB_API_URL or github.com)")
+	fs.StringVar(&f.caBundle, "ca-bundle", "", "PEM `file` of CA certificates to trust in addition to system ones")
+	fs.StringVar(&f.token, "token", "", "GitHub `token` (default from $GH_TOKEN, $GITHUB_TOKEN, gh CLI config or netrc)")
+	fs.Int64Var(&f.appID, "app-id", 0, "authenticate as GitHub App of the `id` instead of token")
+	fs.StringVar(&f.appPrivateKey, "app-private-key", "", "PEM `file` of GitHub App private key")
+	fs.Int64Var(&f.appInstallationID, "app-installation-id", 0, "installation `id` of GitHub App")
+	fs.IntVar(&f.maxAttempts, "max-attempts", defaultMaxAttempts, "`number` of attempts of GitHub API call retried on rate limit and server errors")
+}
+
+func (f *githubFlags) client(ctx context.Context) (*github.Client, error) {
+	baseURL := f.url
+	if baseURL == "" {
+		baseURL = os.Getenv("GITHUB_API_URL")
+	}
+	opt := ClientOptions{
+		BaseURL:  baseURL,
+		CABundle: f.caBundle,
+		Token:    f.token,
+		Retry: RetryOptions{
+			MaxAttempts: f.maxAttempts,
+		},
+	}
+
+	if f.appID != 0 || f.appPrivateKey != "" || f.appInstallationID != 0 {
+		if f.appID == 0 || f.appPrivateKey == "" || f.appInstallationID == 0 {
+			return nil, newUsageError("--app-id, --app-private-key and --app-installation-id are required together")
+		}
+		opt.App = &AppOptions{
+			AppID:          f.appID,
+			PrivateKeyFile: f.appPrivateKey,
+			InstallationID: f.appInstallationID,
+		}
+		return NewGitHubClient(ctx, opt)
+	}
+
+	if opt.Token == "" {
+		t, err := FindToken(webHost(baseURL))
+		if err != nil {
+			return nil, errors.Wrap(err, "--token is empty")
+		}
+		opt.Token = t
+	}
+	return NewGitHubClient(ctx, opt)
+}
+
+// poolFlags are flags to process repositories concurrently.
+type poolFlags struct {
+	concurrency     int
+	continueOnError bool
+}
+
+func (f *poolFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.concurrency, "concurrency", 4, "`number` of repositories processed concurrently")
+	fs.BoolVar(&f.continueOnError, "continue-on-error", false, "keep processing the other repositories when one fails")
+}
+
+func (f *poolFlags) options() PoolOptions {
+	return PoolOptions{
+		Concurrency:     f.concurrency,
+		ContinueOnError: f.continueOnError,
+	}
+}
+
+// selectorFlags are flags to select repositories of an organization.
+type selectorFlags struct {
+	visibility                    string
+	include, exclude              stringsFlag
+	includeRegexp, excludeRegexp  repeatedFlag
+	topics, languages             stringsFlag
+	forks, templates, pushedAfter string
+	reposFile                     string
+}
+
+func (f *selectorFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.visibility, "visibility", "", "`visibility` of repositories: "+strings.Join(visibilities, ", ")+" (default private for organization, all for user account)")
+	fs.Var(&f.include, "include", "only repositories of names matching these glob `patterns` (repeatable, comma separated)")
+	fs.Var(&f.exclude, "exclude", "skip repositories of names matching these glob `patterns` (repeatable, comma separated)")
+	fs.Var(&f.includeRegexp, "include-regexp", "only repositories of names matching any of these `regexps` (repeatable)")
+	fs.Var(&f.excludeRegexp, "exclude-regexp", "skip repositories of names matching any of these `regexps` (repeatable)")
+	fs.Var(&f.topics, "topic", "only repositories having any of these `topics` (repeatable, comma separated)")
+	fs.Var(&f.languages, "language", "only repositories of these primary `languages` (repeatable, comma separated)")
+	fs.StringVar(&f.forks, "forks", includeMode, "`mode` of forks: "+strings.Join(selectModes, ", "))
+	fs.StringVar(&f.templates, "templates", includeMode, "`mode` of template repositories: "+strings.Join(selectModes, ", "))
+	fs.StringVar(&f.pushedAfter, "pushed-after", "", "only repositories pushed after the `date` as YYYY-MM-DD")
+	fs.StringVar(&f.reposFile, "repos-file", "", "`file` of repository names to process, one per line")
+}
+
+func (f *selectorFlags) selector() (*RepoSelector, error) {
+	sel := &RepoSelector{
+		Visibility: f.visibility,
+		Include:    f.include,
+		Exclude:    f.exclude,
+		Topics:     f.topics,
+		Languages:  f.languages,
+		Forks:      f.forks,
+		Templates:  f.templates,
+	}
+	for _, p := range f.includeRegexp {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, newUsageError("invalid --include-regexp %q: %v", p, err)
+		}
+		sel.IncludeRegexp = append(sel.IncludeRegexp, re)
+	}
+	for _, p := range f.excludeRegexp {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, newUsageError("invalid --exclude-regexp %q: %v", p, err)
+		}
+		sel.ExcludeRegexp = append(sel.ExcludeRegexp, re)
+	}
+	if f.pushedAfter != "" {
+		t, err := time.Parse("2006-01-02", f.pushedAfter)
+		if err != nil {
+			return nil, newUsageError("invalid --pushed-after %q, expected YYYY-MM-DD", f.pushedAfter)
+		}
+		sel.PushedAfter = t
+	}
+	if err := sel.Validate(); err != nil {
+		return nil, newUsageError("%v", err)
+	}
+	if f.reposFile != "" {
+		names, err := LoadRepoNames(f.reposFile)
+		if err != nil {
+			return nil, err
+		}
+		sel.Names = names
+	}
+	return sel, nil
+}
+
+func runInspect(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error {
+	var (
+		gh   githubFlags
+		pool poolFlags
+		sf   selectorFlags
+	)
+	gh.register(fs)
+	pool.register(fs)
+	sf.register(fs)
+	var (
+		shadowed, permissions, teams, directMembers, stale bool
+		staleDays                                          int
+		output, branch                                     string
+		ignoreOwners                                       stringsFlag
+	)
+	fs.StringVar(&output, "output", outputLog, "output `format`: "+strings.Join(outputFormats, ", "))
+	fs.StringVar(&branch, "branch", prBranch, "read CODEOWNERS file in the branch `name` if it exists, where replace pushes changes")
+	fs.Var(&ignoreOwners, "ignore-owner", "never report these `owners` (repeatable, comma separated)")
+	fs.BoolVar(&shadowed, "shadowed", false, "look up every CODEOWNERS location and report ignored ones")
+	fs.BoolVar(&permissions, "permissions", false, "report existing owners without write access to repositories they own")
+	fs.BoolVar(&teams, "teams", false, "report team owners which are empty, single member or have no active member")
+	fs.BoolVar(&directMembers, "direct-members", false, "count only direct members of teams, not members of their child teams, with --teams")
+	fs.BoolVar(&stale, "stale", false, "report user owners without commit or review in repositories they own")
+	fs.IntVar(&staleDays, "stale-days", int(defaultStaleWindow/(24*time.Hour)), "`days` to look back for activity with --stale")
+
+	args, cfg, err := parseCommand(fs, args)
+	if err != nil {
+		return err
+	}
+	args = cfg.owners(args)
+	if len(args) == 0 {
+		return newUsageError("expected at least 1 argument, got 0")
+	}
+	if staleDays <= 0 {
+		return newUsageError("--stale-days should be positive, got %d", staleDays)
+	}
+	if !contains(outputFormats, output) {
+		return newUsageError("invalid --output %q", output)
+	}
+	if !isValidBranch(branch) {
+		return newUsageError("invalid --branch %q", branch)
+	}
+	sel, err := sf.selector()
 	if err != nil {
 		return err
 	}
 
-	// TODO: Pass by commandline argument
-	allowlist := map[string]struct{}{
-		"repo": {},
+	owners := splitOwners(args...)
+	if len(owners) == 0 {
+		return newUsageError("no owner is given")
 	}
 
-	// TODO: Pass by commandline argument
-	denylist := map[string]struct{}{
-		"repo": {},
+	cli, err := gh.client(ctx)
+	if err != nil {
+		return err
 	}
-	for _, r := range repos {
-		if _, ok := denylist[r.GetName()]; ok {
-			log.WithField("repo", r.GetName()).Info("denied")
-			continue
+	return inspect(ctx, cli, stdout, owners, output, InspectOptions{
+		Pool:          pool.options(),
+		Repos:         sel,
+		Branch:        branch,
+		Shadowed:      shadowed,
+		Permissions:   permissions,
+		Teams:         teams,
+		DirectMembers: directMembers,
+		Stale:         stale,
+		IgnoreOwners:  trimMentions(ignoreOwners),
+		StaleWindow:   time.Duration(staleDays) * 24 * time.Hour,
+	})
+}
+
+func inspect(ctx context.Context, cli *github.Client, stdout io.Writer, owners []string, output string, opt InspectOptions) error {
+	report, err := InspectAll(ctx, cli, owners, opt)
+	if err != nil {
+		return err
+	}
+	if output != outputLog {
+		logShadowed(report)
+		return writeInspectOutput(stdout, output, newInspectOutput(strings.Join(owners, ","), WebURL(cli), report))
+	}
+
+	for _, o := range report.Owners {
+		logger := log.WithField("owner", o.Name).WithField("kind", o.Kind.String()).WithField("repos", o.OwnRepos).WithField("lines", permalinks(o.Occurrences))
+		if o.Successor != "" {
+			org, _, _ := strings.Cut(o.Name, "/")
+			logger = logger.WithField("suggestion", fmt.Sprintf("possibly absorbed into %s%s (guessed by name)", mentionPrefix, o.Successor)).
+				WithField("command", fmt.Sprintf("codeowners replace %s %s %s", org, o.Name, o.Successor))
 		}
-		if _, ok := allowlist[r.GetName()]; !ok {
-			log.WithField("repo", r.GetName()).Info("denied")
-			continue
+		logger.Info("should be replaced")
+	}
+	for _, o := range report.Ineffective {
+		log.WithField("owner", o.Name).WithField("kind", o.Kind.String()).WithField("repos", o.OwnRepos).WithField("lines", permalinks(o.Occurrences)).Warn("exists but ineffective")
+	}
+	for _, o := range report.External {
+		log.WithField("owner", o.Name).WithField("repos", o.OwnRepos).WithField("lines", permalinks(o.Occurrences)).Info("team of another organization")
+	}
+	for _, t := range report.WeakTeams {
+		logger := log.WithField("owner", t.Name).WithField("issue", t.Issue.String()).WithField("members", t.Members).WithField("repos", t.OwnRepos)
+		if t.Parent != "" {
+			logger = logger.WithField("parent", t.Parent)
 		}
-		content, err := GetCodeownersContent(ctx, cli, r)
-		if errors.Cause(err) == ErrNotFound {
-			log.WithField("repo", r.GetName()).Info("no codeowner file")
-			continue
+		logger.Warn("weak team")
+	}
+	for _, o := range report.Stale {
+		logger := log.WithField("owner", o.Name).WithField("repo", o.Repo).WithField("reason", o.Reason)
+		if !o.LastSeen.IsZero() {
+			logger = logger.WithField("last_seen", o.LastSeen.UTC().Format("2006-01-02"))
+		}
+		logger.Warn("stale owner")
+	}
+	logShadowed(report)
+	return nil
+}
+
+func permalinks(oo []*Occurrence) []string {
+	links := make([]string, len(oo))
+	for i, o := range oo {
+		links[i] = o.Permalink
+	}
+	return links
+}
+
+func logShadowed(report *Report) {
+	for _, rc := range report.Shadowed {
+		log.WithField("repo", rc.Repo.GetName()).WithField("path", rc.Path).WithField("shadowed", rc.Shadowed).Warn("ignored codeowners")
+	}
+}
+
+type replaceOptions struct {
+	repos     *RepoSelector
+	branch    string
+	templates *ReplaceTemplates
+	runID     string
+	reviewers *github.ReviewersRequest
+	labels    []string
+	dryRun    bool
+	pool      PoolOptions
+	shadowed  string
+	stdout    io.Writer
+}
+
+// modes handling CODEOWNERS files ignored by GitHub. shadowedDelete deletes
+// them only with replacements, and shadowedCleanup deletes them in every
+// repository even if no owner is replaced.
+const (
+	shadowedIgnore  = "ignore"
+	shadowedWarn    = "warn"
+	shadowedDelete  = "delete"
+	shadowedCleanup = "cleanup"
+)
+
+func runReplace(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error {
+	var (
+		gh   githubFlags
+		pool poolFlags
+		sf   selectorFlags
+	)
+	gh.register(fs)
+	pool.register(fs)
+	sf.register(fs)
+	var (
+		reviewers, labels        stringsFlag
+		mapPath, prTitle, prBody string
+		commitMessage, runID     string
+		shadowed, branch         string
+		dryRun                   bool
+	)
+	fs.StringVar(&shadowed, "shadowed", shadowedIgnore, "`mode` for CODEOWNERS files ignored by GitHub: ignore, warn, delete along with replacements, or cleanup even without them")
+	fs.BoolVar(&dryRun, "dry-run", false, "print diffs without creating branches, commits and pull requests")
+	fs.StringVar(&mapPath, "map", "", "YAML `file` mapping old owners to new ones, null to remove")
+	fs.Var(&sf.include, "allow", "deprecated alias of --include")
+	fs.Var(&sf.exclude, "deny", "deprecated alias of --exclude")
+	fs.Var(&reviewers, "reviewer", "request review to user or org/team (repeatable, comma separated)")
+	fs.StringVar(&commitMessage, "commit-message", "", "Go `template` of commit message (default is derived from old and new)")
+	fs.StringVar(&prTitle, "pr-title", "", "Go `template` of pull request title (default is the first line of commit message)")
+	fs.StringVar(&prBody, "pr-body", defaultPRBodyTemplate, "Go `template` of pull request body")
+	fs.StringVar(&runID, "run-id", "", "`id` of this run given to templates (default $GITHUB_RUN_ID or the start time)")
+	fs.Var(&labels, "label", "add `labels` to pull requests (repeatable, comma separated)")
+	fs.StringVar(&branch, "branch", prBranch, "branch `name` to push changes and open pull requests from")
+
+	args, cfg, err := parseCommand(fs, args)
+	if err != nil {
+		return err
+	}
+	var rr []Replacement
+	if mapPath != "" {
+		args = cfg.owners(args)
+		if len(args) != 1 {
+			return newUsageError("expected 1 argument with --map, got %d", len(args))
 		}
+		rr, err = LoadReplacements(mapPath)
 		if err != nil {
 			return err
 		}
+	} else {
+		if len(args) < 2 || len(args) > 3 {
+			return newUsageError("expected 2 or 3 arguments, got %d", len(args))
+		}
+		r := Replacement{Old: trimMention(args[1])}
+		if len(args) == 3 {
+			r.New = trimMention(args[2])
+		}
+		rr = append(rr, r)
+	}
+	owners := splitOwners(args[0])
+	if len(owners) == 0 {
+		return newUsageError("no owner is given")
+	}
+	switch shadowed {
+	case shadowedIgnore, shadowedWarn, shadowedDelete, shadowedCleanup:
+	default:
+		return newUsageError("invalid --shadowed %q", shadowed)
+	}
+	if !isValidBranch(branch) {
+		return newUsageError("invalid --branch %q", branch)
+	}
+	sel, err := sf.selector()
+	if err != nil {
+		return err
+	}
+	templates, err := newReplaceTemplates(commitMessage, prTitle, &prBody)
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+	if runID == "" {
+		runID = defaultRunID()
+	}
 
-		s, err := content.GetContent()
+	opt := replaceOptions{
+		repos:     sel,
+		branch:    branch,
+		templates: templates,
+		runID:     runID,
+		reviewers: newReviewersRequest(reviewers),
+		labels:    labels,
+		dryRun:    dryRun,
+		pool:      pool.options(),
+		shadowed:  shadowed,
+		stdout:    stdout,
+	}
+	cli, err := gh.client(ctx)
+	if err != nil {
+		return err
+	}
+	return replace(ctx, cli, owners, rr, opt)
+}
+
+func replace(ctx context.Context, cli *github.Client, owners []string, rr []Replacement, opt replaceOptions) error {
+	repos, err := listRepositories(ctx, cli, owners, opt.repos)
+	if err != nil {
+		return err
+	}
+
+	var changes []*replaceResult
+	err = runPool(ctx, len(repos), opt.pool, func(ctx context.Context, i int) (*replaceResult, error) {
+		res, err := replaceRepo(ctx, cli, repos[i], rr, opt)
 		if err != nil {
+			return nil, errors.Wrap(err, repos[i].GetFullName())
+		}
+		return res, nil
+	}, func(i int, res *replaceResult) {
+		logger := log.WithField("repo", repos[i].GetName())
+		if len(res.shadowed) > 0 && opt.shadowed == shadowedWarn {
+			logger.WithField("path", res.path).WithField("shadowed", res.shadowed).Warn("ignored codeowners")
+		}
+		switch {
+		case res.skipped != "":
+			logger.Info(res.skipped)
+		case opt.dryRun:
+			diff := res.diff
+			if isTerminal(opt.stdout) {
+				diff = ColorizeDiff(diff)
+			}
+			fmt.Fprintf(opt.stdout, "# %s%s%s", res.repo, sep, diff)
+			changes = append(changes, res)
+		default:
+			logger.WithField("url", res.prURL).Info("pr is opened")
+		}
+	})
+
+	if opt.dryRun {
+		if err := printReplaceSummary(opt.stdout, changes); err != nil {
 			return err
 		}
+	}
+	return err
+}
+
+type replaceResult struct {
+	repo    string
+	path    string
+	applied []Replacement
+	// shadowed are paths of CODEOWNERS files ignored by GitHub.
+	shadowed []string
+	// deleted are shadowed ones to be deleted.
+	deleted []string
+	diff    string
+	prURL   string
+	// skipped describes why the repository is skipped.
+	skipped string
+}
 
-		// TODO: Pass by commandline argument
-		o, n := "a", "b"
-		replaced := ReplaceAll(s, o, n)
-		if s == replaced {
-			log.WithField("repo", r.GetName()).Info("no target owner")
+func replaceRepo(ctx context.Context, cli *github.Client, r *github.Repository, rr []Replacement, opt replaceOptions) (*replaceResult, error) {
+	res := &replaceResult{
+		repo: r.GetFullName(),
+	}
+	contents, err := listReplaceContents(ctx, cli, r, opt.branch, opt.shadowed)
+	if errors.Cause(err) == ErrNotFound {
+		res.skipped = "no codeowner file"
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, shadowed := contents[0], contents[1:]
+	for _, c := range shadowed {
+		res.shadowed = append(res.shadowed, c.GetPath())
+	}
+
+	s, err := content.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	f := Parse(s)
+	res.path = content.GetPath()
+	res.applied = f.ReplaceAll(rr)
+	if (opt.shadowed == shadowedDelete && len(res.applied) > 0) || opt.shadowed == shadowedCleanup {
+		res.deleted = res.shadowed
+	}
+	if len(res.applied) == 0 && len(res.deleted) == 0 {
+		res.skipped = "no target owner"
+		return res, nil
+	}
+	replaced := f.String()
+	res.diff = UnifiedDiff(res.path, s, replaced)
+	if len(res.deleted) > 0 {
+		for _, c := range shadowed {
+			old, err := c.GetContent()
+			if err != nil {
+				return nil, err
+			}
+			res.diff += UnifiedDiff(c.GetPath(), old, "")
+		}
+	}
+	if opt.dryRun {
+		return res, nil
+	}
+
+	d := newChangeData(ChangeData{
+		Repo:         r.GetFullName(),
+		Owner:        r.GetOwner().GetLogin(),
+		Name:         r.GetName(),
+		Path:         res.path,
+		FileURL:      fmt.Sprintf("%s/%s/blob/%s/%s", WebURL(cli), r.GetFullName(), opt.branch, res.path),
+		Branch:       opt.branch,
+		Replacements: res.applied,
+		Deleted:      res.deleted,
+		Diff:         res.diff,
+		Lines:        countChangedLines(UnifiedDiff(res.path, s, replaced)),
+		RunID:        opt.runID,
+	})
+	msg, title, body, err := opt.templates.Render(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.applied) > 0 {
+		if err := CreatePatch(ctx, cli, r, opt.branch, content, replaced, github.String(msg)); err != nil {
+			return nil, err
+		}
+	}
+	if len(res.deleted) > 0 {
+		for _, c := range shadowed {
+			if err := DeleteContent(ctx, cli, r, opt.branch, c, fmt.Sprintf("Remove %s ignored by %s", c.GetPath(), res.path)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	pr, err := OpenPR(ctx, cli, r, title, opt.branch, body, opt.reviewers, opt.labels)
+	if err != nil {
+		return nil, err
+	}
+	res.prURL = pr.GetHTMLURL()
+	return res, nil
+}
+
+// listReplaceContents returns CODEOWNERS files to replace in the branch if it
+// exists. The first one is effective and the others are ignored by GitHub,
+// which are looked up unless the mode is shadowedIgnore.
+func listReplaceContents(ctx context.Context, cli *github.Client, r *github.Repository, branch, mode string) ([]*github.RepositoryContent, error) {
+	if mode != shadowedIgnore {
+		return ListCodeownersContents(ctx, cli, r, branch)
+	}
+	content, err := GetCodeownersContent(ctx, cli, r, branch)
+	if err != nil {
+		return nil, err
+	}
+	return []*github.RepositoryContent{content}, nil
+}
+
+// printReplaceSummary prints a table of repositories which would change.
+func printReplaceSummary(w io.Writer, changes []*replaceResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s%d repositories would change%s", sep, len(changes), sep)
+	if len(changes) == 0 {
+		return tw.Flush()
+	}
+	fmt.Fprintf(tw, "REPOSITORY\tFILE\tREPLACEMENTS%s", sep)
+	for _, c := range changes {
+		rr := make([]string, len(c.applied))
+		for i, r := range c.applied {
+			rr[i] = r.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s%s", c.repo, c.path, strings.Join(rr, ", "), sep)
+	}
+	return tw.Flush()
+}
+
+func trimMentions(ss []string) []string {
+	trimmed := make([]string, len(ss))
+	for i, s := range ss {
+		trimmed[i] = trimMention(s)
+	}
+	return trimmed
+}
+
+// newReviewersRequest splits reviewers into users and teams. Teams are
+// written as "org/team" and requested by its slug.
+func newReviewersRequest(reviewers []string) *github.ReviewersRequest {
+	if len(reviewers) == 0 {
+		return nil
+	}
+	req := &github.ReviewersRequest{}
+	for _, r := range reviewers {
+		r = trimMention(r)
+		if i := strings.Index(r, "/"); i >= 0 {
+			req.TeamReviewers = append(req.TeamReviewers, r[i+1:])
 			continue
 		}
+		req.Reviewers = append(req.Reviewers, r)
+	}
+	return req
+}
+
+// newReplaceTemplates parses templates of replace. Empty commit message and
+// title are defaults, as well as nil body. Empty body is kept empty.
+func newReplaceTemplates(commitMessage, prTitle string, prBody *string) (*ReplaceTemplates, error) {
+	if commitMessage == "" {
+		commitMessage = defaultCommitMessageTemplate
+	}
+	if prTitle == "" {
+		prTitle = defaultPRTitleTemplate
+	}
+	body := defaultPRBodyTemplate
+	if prBody != nil {
+		body = *prBody
+	}
+	return NewReplaceTemplates(commitMessage, prTitle, body)
+}
+
+func runWhoOwns(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error {
+	var gh githubFlags
+	gh.register(fs)
+	var ref string
+	fs.StringVar(&ref, "ref", "", "branch, tag or commit `ref` to read CODEOWNERS (default is the default branch)")
 
-		log.WithField("repo", r.GetName()).WithField("after", replaced).Info("replaced")
+	args, _, err := parseCommand(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return newUsageError("expected at least 2 arguments, got %d", len(args))
+	}
+
+	cli, err := gh.client(ctx)
+	if err != nil {
+		return err
+	}
+	r, err := GetRepository(ctx, cli, args[0])
+	if err != nil {
+		return err
+	}
+	content, err := GetCodeownersContentAt(ctx, cli, r, optionalRef(ref))
+	if err != nil {
+		return err
+	}
+	s, err := content.GetContent()
+	if err != nil {
+		return errors.Wrap(err, "content.GetContent")
+	}
+	oo, err := WhoOwns(Parse(s), args[1:])
+	if err != nil {
+		return errors.Wrap(err, content.GetPath())
+	}
+	return printOwnerships(stdout, content.GetPath(), oo)
+}
 
-		msg := github.String(fmt.Sprintf("Update %s to %s", o, n))
-		// TODO: Support remove command
-		if n == "" {
-			msg = github.String(fmt.Sprintf("Remove %s", o))
+// printOwnerships prints a table of effective owners of each path. Path
+// without any matching rule or owner is printed with "-".
+func printOwnerships(w io.Writer, path string, oo []*Ownership) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "PATH\tLINE\tPATTERN\tOWNERS%s", sep)
+	for _, o := range oo {
+		if o.Rule == nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-%s", o.Path, sep)
+			continue
 		}
-		if err := CreatePatch(ctx, cli, r, content, replaced, msg); err != nil {
-			return err
+		owners := make([]string, 0, len(o.Rule.Owners))
+		for _, owner := range o.Rule.Owners {
+			owners = append(owners, owner.Token)
 		}
+		if len(owners) == 0 {
+			owners = append(owners, "-")
+		}
+		fmt.Fprintf(tw, "%s\t%s:%d\t%s\t%s%s", o.Path, path, o.Rule.Line, o.Rule.Pattern, strings.Join(owners, " "), sep)
+	}
+	return tw.Flush()
+}
 
-		// TODO: Pass by commandline option (e.g. --pr-body "text")
-		body := `
-Update codeowners.
-`
-		// TODO: Pass by commandline option (e.g. --pr-title "text")
-		if _, err := OpenPR(ctx, cli, r, "pr title", "update-codeowners", body, &github.ReviewersRequest{
-			// TODO: Request review "a", "b" either person or team
-			Reviewers:     []string{"a"},
-			TeamReviewers: []string{"b"},
-		}); err != nil {
-			return err
+func runCoverage(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error {
+	var (
+		gh   githubFlags
+		pool poolFlags
+		sf   selectorFlags
+	)
+	gh.register(fs)
+	pool.register(fs)
+	sf.register(fs)
+	var (
+		ref         string
+		minCoverage float64
+		top         int
+	)
+	fs.StringVar(&ref, "ref", "", "branch, tag or commit `ref` to measure (default is the default branch)")
+	fs.Float64Var(&minCoverage, "min-coverage", 0, "fail if `percent` of owned files of any repository is less than this")
+	fs.IntVar(&top, "top", 10, "`number` of the largest unowned subtrees to print")
+
+	args, cfg, err := parseCommand(fs, args)
+	if err != nil {
+		return err
+	}
+	args = cfg.owners(args)
+	if len(args) != 1 {
+		return newUsageError("expected 1 argument, got %d", len(args))
+	}
+	if minCoverage < 0 || minCoverage > 100 {
+		return newUsageError("--min-coverage should be between 0 and 100, got %v", minCoverage)
+	}
+	sel, err := sf.selector()
+	if err != nil {
+		return err
+	}
+
+	cli, err := gh.client(ctx)
+	if err != nil {
+		return err
+	}
+	repos, err := listTargetRepositories(ctx, cli, args[0], sel)
+	if err != nil {
+		return err
+	}
+	refp := optionalRef(ref)
+
+	var (
+		below   multiError
+		printed bool
+	)
+	err = runPool(ctx, len(repos), pool.options(), func(ctx context.Context, i int) (*Coverage, error) {
+		c, err := GetRepoCoverage(ctx, cli, repos[i], refp)
+		if err != nil {
+			return nil, errors.Wrap(err, repos[i].GetFullName())
+		}
+		return c, nil
+	}, func(i int, c *Coverage) {
+		name := repos[i].GetFullName()
+		if printed {
+			fmt.Fprint(stdout, sep)
+		}
+		printed = true
+		if err := printCoverage(stdout, name, c, top); err != nil {
+			log.WithError(err).WithField("repo", name).Error("failed to print coverage")
+		}
+		if c.FilePercent() < minCoverage {
+			below = append(below, errors.Errorf("coverage of %s is %.1f%%, less than %.1f%%", name, c.FilePercent(), minCoverage))
 		}
-		log.WithField("repo", r.GetName()).Info("pr is opened")
+	})
+	if err != nil {
+		return err
+	}
+	if len(below) > 0 {
+		return below
+	}
+	return nil
+}
+
+// printCoverage prints coverage of the repository followed by at most top
+// unowned subtrees.
+func printCoverage(w io.Writer, repo string, c *Coverage, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "# %s%s", repo, sep)
+	fmt.Fprintf(tw, "files\t%.1f%%\t(%d/%d)%s", c.FilePercent(), c.OwnedFiles, c.Files, sep)
+	fmt.Fprintf(tw, "directories\t%.1f%%\t(%d/%d)%s", c.DirPercent(), c.OwnedDirs, c.Dirs, sep)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
 
-		time.Sleep(3 * time.Second)
+	unowned := c.Unowned
+	if top >= 0 && len(unowned) > top {
+		unowned = unowned[:top]
+	}
+	if len(unowned) == 0 {
+		return nil
 	}
+	fmt.Fprintf(tw, "%sUNOWNED\tFILES%s", sep, sep)
+	for _, u := range unowned {
+		fmt.Fprintf(tw, "%s\t%d%s", u.Path, u.Files, sep)
+	}
+	return tw.Flush()
+}
+
+func runLint(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error {
+	var (
+		gh   githubFlags
+		pool poolFlags
+		sf   selectorFlags
+	)
+	gh.register(fs)
+	pool.register(fs)
+	sf.register(fs)
+	var ref string
+	fs.StringVar(&ref, "ref", "", "branch, tag or commit `ref` to lint (default is the default branch)")
 
+	args, cfg, err := parseCommand(fs, args)
+	if err != nil {
+		return err
+	}
+	args = cfg.owners(args)
+	if len(args) != 1 {
+		return newUsageError("expected 1 argument, got %d", len(args))
+	}
+	sel, err := sf.selector()
+	if err != nil {
+		return err
+	}
+
+	cli, err := gh.client(ctx)
+	if err != nil {
+		return err
+	}
+	repos, err := listTargetRepositories(ctx, cli, args[0], sel)
+	if err != nil {
+		return err
+	}
+	refp := optionalRef(ref)
+
+	problems := 0
+	err = runPool(ctx, len(repos), pool.options(), func(ctx context.Context, i int) (*RepoLint, error) {
+		res, err := LintRepo(ctx, cli, repos[i], refp)
+		if errors.Cause(err) == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, repos[i].GetFullName())
+		}
+		return res, nil
+	}, func(i int, res *RepoLint) {
+		if res == nil {
+			log.WithField("repo", repos[i].GetName()).Info("no codeowners")
+			return
+		}
+		problems += len(res.Problems)
+		printProblems(stdout, repos[i].GetFullName(), res)
+	})
+	if err != nil {
+		return err
+	}
+	if problems > 0 {
+		return errors.Errorf("%d problems found", problems)
+	}
 	return nil
 }
+
+// printProblems prints a problem per line prefixed by its location.
+func printProblems(w io.Writer, repo string, res *RepoLint) {
+	for _, p := range res.Problems {
+		fmt.Fprintf(w, "%s:%s:%d: %s; %s%s", repo, res.Path, p.Rule.Line, p.Message(), p.Fix(), sep)
+	}
+}
+
+// listTargetRepositories returns the repository if target is "owner/repo",
+// or repositories of comma separated owners selected by sel otherwise.
+func listTargetRepositories(ctx context.Context, cli *github.Client, target string, sel *RepoSelector) ([]*github.Repository, error) {
+	if !strings.Contains(target, "/") {
+		return listRepositories(ctx, cli, splitOwners(target), sel)
+	}
+	r, err := GetRepository(ctx, cli, target)
+	if err != nil {
+		return nil, err
+	}
+	return []*github.Repository{r}, nil
+}
+
+// listRepositories returns repositories of every owner selected by sel, in
+// order of owners.
+func listRepositories(ctx context.Context, cli *github.Client, owners []string, sel *RepoSelector) ([]*github.Repository, error) {
+	var all []*github.Repository
+	for _, owner := range owners {
+		rr, err := ListActivatedRepositories(ctx, cli, owner, sel)
+		if err != nil {
+			return nil, errors.Wrap(err, owner)
+		}
+		all = append(all, rr...)
+	}
+	return all, nil
+}
+
+// splitOwners splits each of comma separated owners.
+func splitOwners(args ...string) []string {
+	var owners stringsFlag
+	for _, a := range args {
+		_ = owners.Set(a)
+	}
+	return owners
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalRef returns nil for the default branch if ref is empty.
+func optionalRef(ref string) *string {
+	if ref == "" {
+		return nil
+	}
+	return &ref
+}
+
+func runSuggest(ctx context.Context, stdout io.Writer, fs *flag.FlagSet, args []string) error {
+	var (
+		gh   githubFlags
+		pool poolFlags
+	)
+	gh.register(fs)
+	pool.register(fs)
+	var (
+		days, maxCommits, maxPulls, top int
+		patch                           bool
+	)
+	fs.IntVar(&days, "days", int(defaultSuggestWindow/(24*time.Hour)), "`days` to look back for commits and reviews")
+	fs.IntVar(&maxCommits, "max-commits", defaultSuggestMaxCommits, "`number` of the latest commits to look up at most")
+	fs.IntVar(&maxPulls, "max-pulls", defaultSuggestMaxPulls, "`number` of the latest closed pull requests to look up reviews at most")
+	fs.IntVar(&top, "top", defaultSuggestTop, "`number` of candidates per rule")
+	fs.BoolVar(&patch, "patch", false, "print a patch setting the top candidate as owner of rules without valid owner")
+
+	args, _, err := parseCommand(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return newUsageError("expected 1 argument, got %d", len(args))
+	}
+	if days <= 0 {
+		return newUsageError("--days should be positive, got %d", days)
+	}
+
+	cli, err := gh.client(ctx)
+	if err != nil {
+		return err
+	}
+	r, err := GetRepository(ctx, cli, args[0])
+	if err != nil {
+		return err
+	}
+	res, err := Suggest(ctx, cli, r, SuggestOptions{
+		Pool:       pool.options(),
+		Since:      time.Now().Add(-time.Duration(days) * 24 * time.Hour),
+		MaxCommits: maxCommits,
+		MaxPulls:   maxPulls,
+		Top:        top,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !patch {
+		return printSuggestions(stdout, res.Suggestions)
+	}
+	before := res.File.String()
+	if res.File.ProposeOwners(res.Suggestions) == 0 {
+		log.WithField("repo", r.GetName()).Info("no rule to propose")
+		return nil
+	}
+	diff := UnifiedDiff(res.Path, before, res.File.String())
+	if isTerminal(stdout) {
+		diff = ColorizeDiff(diff)
+	}
+	fmt.Fprint(stdout, diff)
+	return nil
+}
+
+// printSuggestions prints a table of candidates of each rule. Owners of
+// orphan rule are marked as missing, and unowned rules have no line.
+func printSuggestions(w io.Writer, ss []*Suggestion) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "LINE\tPATTERN\tOWNERS\tCANDIDATES%s", sep)
+	for _, s := range ss {
+		owners := make([]string, 0, len(s.Rule.Owners))
+		for _, o := range s.Rule.Owners {
+			owners = append(owners, o.Token)
+		}
+		if len(owners) == 0 {
+			owners = append(owners, "-")
+		} else if s.Orphan {
+			owners = append(owners, "(missing)")
+		}
+		cc := make([]string, len(s.Candidates))
+		for i, c := range s.Candidates {
+			cc[i] = fmt.Sprintf("%s%s %.0f%% (commits %d, reviews %d)", mentionPrefix, c.Name, c.Share*100, c.Commits, c.Reviews)
+			if c.NoWriteAccess {
+				cc[i] += " without write access"
+			}
+		}
+		if len(cc) == 0 {
+			cc = append(cc, "-")
+		}
+		line := "-"
+		if !s.Unowned {
+			line = fmt.Sprint(s.Rule.Line)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s%s", line, s.Rule.Pattern, strings.Join(owners, " "), strings.Join(cc, ", "), sep)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_run(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{
+			name:     "no command",
+			args:     nil,
+			expected: exitUsage,
+		},
+		{
+			name:     "help",
+			args:     []string{"help"},
+			expected: exitOK,
+		},
+		{
+			name:     "command help",
+			args:     []string{"replace", "-h"},
+			expected: exitOK,
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"unknown"},
+			expected: exitUsage,
+		},
+		{
+			name:     "missing argument",
+			args:     []string{"inspect"},
+			expected: exitUsage,
+		},
+		{
+			name:     "too many arguments",
+			args:     []string{"replace", "org", "a", "b", "c"},
+			expected: exitUsage,
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"inspect", "--unknown", "org"},
+			expected: exitUsage,
+		},
+		{
+			name:     "invalid output",
+			args:     []string{"inspect", "--output", "xml", "org"},
+			expected: exitUsage,
+		},
+		{
+			name:     "no owner",
+			args:     []string{"inspect", ","},
+			expected: exitUsage,
+		},
+		{
+			name:     "missing config",
+			args:     []string{"lint", "--config", "missing.yaml", "org"},
+			expected: exitError,
+		},
+		{
+			name:     "invalid branch",
+			args:     []string{"replace", "--branch", "a..b", "org", "a"},
+			expected: exitUsage,
+		},
+		{
+			name:     "invalid visibility",
+			args:     []string{"lint", "--visibility", "secret", "org"},
+			expected: exitUsage,
+		},
+		{
+			name:     "invalid regexp",
+			args:     []string{"coverage", "--include-regexp", "(", "org"},
+			expected: exitUsage,
+		},
+		{
+			name:     "invalid template",
+			args:     []string{"replace", "--pr-body", "{{.Unknown}}", "org", "a"},
+			expected: exitUsage,
+		},
+		{
+			name:     "invalid pushed after",
+			args:     []string{"replace", "--pushed-after", "yesterday", "org", "a"},
+			expected: exitUsage,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			got := run(tc.args, &stdout, &stderr)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func Test_parseArgs(t *testing.T) {
+	cases := []struct {
+		name          string
+		args          []string
+		expected      []string
+		expectedAllow []string
+	}{
+		{
+			name:     "positional only",
+			args:     []string{"org", "a", "b"},
+			expected: []string{"org", "a", "b"},
+		},
+		{
+			name:          "interspersed flags",
+			args:          []string{"org", "--allow", "x,y", "a", "--allow=z", "b"},
+			expected:      []string{"org", "a", "b"},
+			expectedAllow: []string{"x", "y", "z"},
+		},
+		{
+			name:     "double dash",
+			args:     []string{"org", "--", "--allow", "a"},
+			expected: []string{"org", "--allow", "a"},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var allow stringsFlag
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Var(&allow, "allow", "")
+
+			got, err := parseArgs(fs, tc.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expectedAllow, []string(allow))
+		})
+	}
+}
+
+func Test_newReviewersRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		given    []string
+		expected *github.ReviewersRequest
+	}{
+		{
+			name:     "empty",
+			given:    nil,
+			expected: nil,
+		},
+		{
+			name:  "users and teams",
+			given: []string{"a", "@b", "org/team", "@org/team2"},
+			expected: &github.ReviewersRequest{
+				Reviewers:     []string{"a", "b"},
+				TeamReviewers: []string{"team", "team2"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := newReviewersRequest(tc.given)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func Test_replaceRepo(t *testing.T) {
+	cases := []struct {
+		name            string
+		rr              []Replacement
+		shadowed        string
+		expectedSkipped string
+		expectedDeleted []string
+	}{
+		{
+			name:            "delete with replacement",
+			rr:              []Replacement{{Old: "a", New: "b"}},
+			shadowed:        shadowedDelete,
+			expectedDeleted: []string{"docs/CODEOWNERS"},
+		},
+		{
+			name:            "delete without replacement",
+			rr:              []Replacement{{Old: "z", New: "b"}},
+			shadowed:        shadowedDelete,
+			expectedSkipped: "no target owner",
+		},
+		{
+			name:            "cleanup without replacement",
+			rr:              []Replacement{{Old: "z", New: "b"}},
+			shadowed:        shadowedCleanup,
+			expectedDeleted: []string{"docs/CODEOWNERS"},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				prefix := "/api/v3/repos/org/repo/contents/"
+				if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, prefix) {
+					p := strings.TrimPrefix(r.URL.Path, prefix)
+					if p != "CODEOWNERS" && p != "docs/CODEOWNERS" {
+						rw.WriteHeader(http.StatusNotFound)
+						return
+					}
+					rw.Header().Set("Content-Type", "application/json")
+					_, err := io.WriteString(rw, fmt.Sprintf(`{"path": "%s", "content": "* @a"}`, p))
+					require.NoError(t, err)
+					return
+				}
+				t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			}))
+			defer server.Close()
+
+			mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+			require.NoError(t, err)
+			repo := &github.Repository{
+				Owner:         &github.User{Login: github.String("org")},
+				Name:          github.String("repo"),
+				FullName:      github.String("org/repo"),
+				DefaultBranch: github.String("main"),
+			}
+
+			got, err := replaceRepo(context.Background(), mockGithubCli, repo, tc.rr, replaceOptions{shadowed: tc.shadowed, dryRun: true})
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSkipped, got.skipped)
+			assert.Equal(t, []string{"docs/CODEOWNERS"}, got.shadowed)
+			assert.Equal(t, tc.expectedDeleted, got.deleted)
+		})
+	}
+}
+
+func Test_printReplaceSummary(t *testing.T) {
+	var b bytes.Buffer
+	err := printReplaceSummary(&b, []*replaceResult{
+		{
+			repo:    "org/repo",
+			path:    ".github/CODEOWNERS",
+			applied: []Replacement{{Old: "a", New: "b"}, {Old: "c"}},
+		},
+		{
+			repo:    "org/another-repo",
+			path:    "CODEOWNERS",
+			applied: []Replacement{{Old: "a", New: "b"}},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, `
+2 repositories would change
+REPOSITORY        FILE                REPLACEMENTS
+org/repo          .github/CODEOWNERS  Update a to b, Remove c
+org/another-repo  CODEOWNERS          Update a to b
+`, b.String())
+}
+
+func Test_printOwnerships(t *testing.T) {
+	f := Parse("* @a\n/docs/ @org/docs docs@example.com\n/vendor/\n")
+	oo, err := WhoOwns(f, []string{"main.go", "docs/a.md", "vendor/b.go"})
+	require.NoError(t, err)
+	oo = append(oo, &Ownership{Path: "unowned"})
+
+	var b bytes.Buffer
+	err = printOwnerships(&b, "CODEOWNERS", oo)
+
+	require.NoError(t, err)
+	assert.Equal(t, `PATH         LINE          PATTERN   OWNERS
+main.go      CODEOWNERS:1  *         @a
+docs/a.md    CODEOWNERS:2  /docs/    @org/docs docs@example.com
+vendor/b.go  CODEOWNERS:3  /vendor/  -
+unowned      -             -         -
+`, b.String())
+}
+
+func Test_printCoverage(t *testing.T) {
+	var b bytes.Buffer
+	err := printCoverage(&b, "org/repo", &Coverage{
+		Files:      10,
+		OwnedFiles: 7,
+		Dirs:       3,
+		OwnedDirs:  2,
+		Unowned: []*UnownedTree{
+			{Path: "vendor/", Files: 2},
+			{Path: "README.md", Files: 1},
+		},
+	}, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, `# org/repo
+files        70.0%  (7/10)
+directories  66.7%  (2/3)
+
+UNOWNED  FILES
+vendor/  2
+`, b.String())
+}
+
+func Test_printProblems(t *testing.T) {
+	var b bytes.Buffer
+	printProblems(&b, "org/repo", &RepoLint{
+		Path: ".github/CODEOWNERS",
+		Problems: []*Problem{
+			{Rule: &Rule{Line: 2, Pattern: "/legacy/"}, Kind: DeadRule},
+		},
+	})
+
+	assert.Equal(t, `org/repo:.github/CODEOWNERS:2: dead rule "/legacy/" matches no file; remove the line
+`, b.String())
+}
+
+func Test_printSuggestions(t *testing.T) {
+	rules := Parse("* @a\n/docs/ @gone\n/vendor/\n").Rules()
+
+	var b bytes.Buffer
+	err := printSuggestions(&b, []*Suggestion{
+		{Rule: rules[0], Candidates: []*Candidate{{Name: "a", Commits: 3, Share: 0.75}, {Name: "org/team", Reviews: 1, Share: 0.25}}},
+		{Rule: rules[1], Orphan: true, Candidates: []*Candidate{{Name: "b", Commits: 1, Reviews: 1, Share: 1}}},
+		{Rule: rules[2], Orphan: true},
+		{Rule: &Rule{Pattern: "/src/"}, Unowned: true, Orphan: true, Candidates: []*Candidate{{Name: "c", Commits: 2, Share: 1, NoWriteAccess: true}}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, `LINE  PATTERN   OWNERS           CANDIDATES
+1     *         @a               @a 75% (commits 3, reviews 0), @org/team 25% (commits 0, reviews 1)
+2     /docs/    @gone (missing)  @b 100% (commits 1, reviews 1)
+3     /vendor/  -                -
+-     /src/     -                @c 100% (commits 2, reviews 0) without write access
+`, b.String())
+}
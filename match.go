@@ -0,0 +1,160 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Matcher finds rules of CODEOWNERS file matching paths the way GitHub does,
+// which follows gitignore rules mostly.
+type Matcher struct {
+	rules    []*Rule
+	patterns []*regexp.Regexp
+}
+
+// NewMatcher compiles patterns of every rule in the file.
+func NewMatcher(f *File) (*Matcher, error) {
+	rules := f.Rules()
+	m := &Matcher{
+		rules:    rules,
+		patterns: make([]*regexp.Regexp, len(rules)),
+	}
+	for i, r := range rules {
+		re, err := compilePattern(r.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", r.Line)
+		}
+		m.patterns[i] = re
+	}
+	return m, nil
+}
+
+// Match returns the last rule matching path, or nil if nothing matches. Path
+// is relative to the repository root, and directory ends with "/".
+func (m *Matcher) Match(path string) *Rule {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		if m.patterns[i].MatchString(path) {
+			return m.rules[i]
+		}
+	}
+	return nil
+}
+
+// MatchAll returns every rule matching path in order.
+func (m *Matcher) MatchAll(path string) []*Rule {
+	path = strings.TrimPrefix(path, "/")
+	var rr []*Rule
+	for i, re := range m.patterns {
+		if re.MatchString(path) {
+			rr = append(rr, m.rules[i])
+		}
+	}
+	return rr
+}
+
+// compilePattern converts gitignore style pattern into regular expression
+// matching the path itself and everything under it.
+//
+//   - Pattern starting with "/" or having "/" in the middle is relative to the
+//     root. Otherwise it matches at any level.
+//   - Pattern ending with "/" matches only directory, so everything under it.
+//   - "*" matches anything except "/", and "?" matches any one character
+//     except "/".
+//   - "**/" matches zero or more directories, and "/**" matches everything
+//     inside.
+//   - Pattern ending with "/*" matches only files directly in the directory,
+//     not in its subdirectories, as GitHub documents.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	dirOnly := strings.HasSuffix(p, "/") && !strings.HasSuffix(p, `\/`)
+	p = strings.TrimRight(p, "/")
+	anchored := strings.HasPrefix(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if strings.Contains(p, "/") {
+		anchored = true
+	}
+	if p == "" {
+		return nil, errors.Errorf("invalid pattern %q", pattern)
+	}
+	singleLevel := !dirOnly && strings.HasSuffix(p, "/*")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		case strings.HasPrefix(p[i:], "**/") && (i == 0 || p[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case p[i:] == "**" && i > 0 && p[i-1] == '/':
+			b.WriteString(".*")
+			i++
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case singleLevel:
+		b.WriteString("$")
+	default:
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
+
+// Ownership is the effective rule of a path.
+type Ownership struct {
+	Path string
+	// Rule is nil if no rule matches the path.
+	Rule *Rule
+}
+
+// Owners returns names of effective owners. It's empty if the path has no
+// owner.
+func (o *Ownership) Owners() []string {
+	if o.Rule == nil {
+		return nil
+	}
+	nn := make([]string, 0, len(o.Rule.Owners))
+	for _, owner := range o.Rule.Owners {
+		if owner.Kind() == UnknownOwner {
+			continue
+		}
+		nn = append(nn, owner.Name())
+	}
+	return nn
+}
+
+// WhoOwns returns the effective rule of each path in the file.
+func WhoOwns(f *File, paths []string) ([]*Ownership, error) {
+	m, err := NewMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+	oo := make([]*Ownership, len(paths))
+	for i, p := range paths {
+		oo[i] = &Ownership{
+			Path: p,
+			Rule: m.Match(p),
+		}
+	}
+	return oo, nil
+}
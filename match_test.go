@@ -0,0 +1,154 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_compilePattern(t *testing.T) {
+	cases := []struct {
+		name      string
+		pattern   string
+		matched   []string
+		unmatched []string
+	}{
+		{
+			name:    "everything",
+			pattern: "*",
+			matched: []string{"a", "a/b/c.go", ".github/CODEOWNERS"},
+		},
+		{
+			name:      "extension at any level",
+			pattern:   "*.js",
+			matched:   []string{"a.js", "src/a.js", "src/lib/a.js"},
+			unmatched: []string{"a.jsx", "a.js.map"},
+		},
+		{
+			name:      "name at any level",
+			pattern:   "apps",
+			matched:   []string{"apps", "apps/a", "src/apps/a/b"},
+			unmatched: []string{"myapps/a", "apps2"},
+		},
+		{
+			name:      "directory at any level",
+			pattern:   "apps/",
+			matched:   []string{"apps/a", "src/apps/a/b", "apps/"},
+			unmatched: []string{"apps", "src/apps"},
+		},
+		{
+			name:      "anchored directory",
+			pattern:   "/docs/",
+			matched:   []string{"docs/a.md", "docs/build/a.md"},
+			unmatched: []string{"src/docs/a.md", "docs"},
+		},
+		{
+			name:      "anchored by middle slash",
+			pattern:   "docs/*",
+			matched:   []string{"docs/getting-started.md"},
+			unmatched: []string{"src/docs/a.md", "docs/build-app/troubleshooting.md"},
+		},
+		{
+			name:      "leading double asterisk",
+			pattern:   "**/logs",
+			matched:   []string{"logs", "logs/a", "build/logs/a", "a/b/logs"},
+			unmatched: []string{"mylogs"},
+		},
+		{
+			name:      "trailing double asterisk",
+			pattern:   "/build/**",
+			matched:   []string{"build/a", "build/a/b"},
+			unmatched: []string{"src/build/a"},
+		},
+		{
+			name:      "middle double asterisk",
+			pattern:   "a/**/b",
+			matched:   []string{"a/b", "a/x/b", "a/x/y/b/c"},
+			unmatched: []string{"ab", "x/a/b"},
+		},
+		{
+			name:      "question mark",
+			pattern:   "?.go",
+			matched:   []string{"a.go", "src/b.go"},
+			unmatched: []string{"ab.go", "/.go"},
+		},
+		{
+			name:      "escaped space",
+			pattern:   `/a\ b/`,
+			matched:   []string{"a b/c"},
+			unmatched: []string{"a/c"},
+		},
+		{
+			name:      "escaped asterisk",
+			pattern:   `\*.go`,
+			matched:   []string{"*.go"},
+			unmatched: []string{"a.go"},
+		},
+		{
+			name:      "regexp meta characters",
+			pattern:   "a+b.(c)",
+			matched:   []string{"a+b.(c)"},
+			unmatched: []string{"aab.(c)", "a+bx(c)"},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			re, err := compilePattern(tc.pattern)
+			require.NoError(t, err)
+
+			for _, p := range tc.matched {
+				assert.True(t, re.MatchString(p), p)
+			}
+			for _, p := range tc.unmatched {
+				assert.False(t, re.MatchString(p), p)
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := compilePattern("/")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWhoOwns(t *testing.T) {
+	f := Parse(`# default
+* @org/all
+*.js @js-owner # inline comment
+/docs/ @docs docs@example.com
+/docs/generated/
+/build/**/logs @org/ops
+`)
+
+	got, err := WhoOwns(f, []string{"README.md", "src/a.js", "docs/a.js", "docs/generated/a.md", "/build/x/logs/1"})
+
+	require.NoError(t, err)
+	lines := make([]int, len(got))
+	owners := make([][]string, len(got))
+	for i, o := range got {
+		lines[i] = o.Rule.Line
+		owners[i] = o.Owners()
+	}
+	assert.Equal(t, []int{2, 3, 4, 5, 6}, lines)
+	assert.Equal(t, [][]string{
+		{"org/all"},
+		{"js-owner"},
+		{"docs", "docs@example.com"},
+		{},
+		{"org/ops"},
+	}, owners)
+}
+
+func TestWhoOwns_unowned(t *testing.T) {
+	f := Parse("/src/ @a\n")
+
+	got, err := WhoOwns(f, []string{"README.md"})
+
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Nil(t, got[0].Rule)
+	assert.Nil(t, got[0].Owners())
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// output formats of inspect
+const (
+	outputLog      = "log"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputCSV      = "csv"
+	outputMarkdown = "markdown"
+	outputTable    = "table"
+)
+
+var outputFormats = []string{outputLog, outputJSON, outputYAML, outputCSV, outputMarkdown, outputTable}
+
+// statuses of owner in inspect output
+const (
+	statusMissing     = "missing"
+	statusIneffective = "ineffective"
+	statusWeak        = "weak"
+	statusStale       = "stale"
+	statusExternal    = "external"
+)
+
+// InspectOutput is the document written by inspect in JSON or YAML, which
+// follows inspect.schema.json. Organization is comma separated owners if
+// many are inspected.
+type InspectOutput struct {
+	Organization string         `json:"organization" yaml:"organization"`
+	Owners       []*OwnerStatus `json:"owners" yaml:"owners"`
+}
+
+// OwnerStatus is a problem of an owner found by inspect.
+type OwnerStatus struct {
+	Name   string `json:"name" yaml:"name"`
+	Kind   string `json:"kind" yaml:"kind"`
+	Status string `json:"status" yaml:"status"`
+	// Detail describes the status, e.g. suggested successor of missing owner.
+	Detail   string   `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Members  []string `json:"members,omitempty" yaml:"members,omitempty"`
+	LastSeen string   `json:"last_seen,omitempty" yaml:"last_seen,omitempty"`
+	Repos    []string `json:"repos" yaml:"repos"`
+	// Lines are references to rules of the owner in Repos as
+	// "repo:path:line".
+	Lines       []string      `json:"lines" yaml:"lines"`
+	Occurrences []*Occurrence `json:"occurrences" yaml:"occurrences"`
+}
+
+// newInspectOutput flattens the report in order of name, status and
+// repositories. webURL is used to make permalinks.
+func newInspectOutput(org, webURL string, report *Report) *InspectOutput {
+	var oo []*OwnerStatus
+	for _, o := range report.Owners {
+		s := &OwnerStatus{Name: o.Name, Kind: o.Kind.String(), Status: statusMissing, Repos: o.OwnRepos}
+		if o.Successor != "" {
+			s.Detail = fmt.Sprintf("possibly absorbed into %s%s (guessed by name)", mentionPrefix, o.Successor)
+		}
+		oo = append(oo, s)
+	}
+	for _, o := range report.Ineffective {
+		oo = append(oo, &OwnerStatus{Name: o.Name, Kind: o.Kind.String(), Status: statusIneffective, Detail: "no write access", Repos: o.OwnRepos})
+	}
+	for _, o := range report.External {
+		oo = append(oo, &OwnerStatus{Name: o.Name, Kind: o.Kind.String(), Status: statusExternal, Detail: "team of another organization", Repos: o.OwnRepos})
+	}
+	for _, t := range report.WeakTeams {
+		s := &OwnerStatus{Name: t.Name, Kind: TeamOwner.String(), Status: statusWeak, Detail: t.Issue.String(), Members: t.Members, Repos: t.OwnRepos}
+		if t.Parent != "" {
+			s.Detail += ", parent " + mentionPrefix + t.Parent
+		}
+		oo = append(oo, s)
+	}
+	for _, o := range report.Stale {
+		s := &OwnerStatus{Name: o.Name, Kind: UserOwner.String(), Status: statusStale, Detail: o.Reason, Repos: []string{o.Repo}}
+		if !o.LastSeen.IsZero() {
+			s.LastSeen = o.LastSeen.UTC().Format("2006-01-02")
+		}
+		oo = append(oo, s)
+	}
+
+	for _, s := range oo {
+		s.Repos = append([]string{}, s.Repos...)
+		sort.Strings(s.Repos)
+		s.Occurrences = listOccurrences(report.Repos, s.Name, s.Repos, webURL)
+		s.Lines = make([]string, len(s.Occurrences))
+		for i, o := range s.Occurrences {
+			s.Lines[i] = o.Repo + ":" + o.Path + ":" + strconv.Itoa(o.Line)
+		}
+	}
+	sort.SliceStable(oo, func(i, j int) bool {
+		if oo[i].Name != oo[j].Name {
+			return oo[i].Name < oo[j].Name
+		}
+		if oo[i].Status != oo[j].Status {
+			return oo[i].Status < oo[j].Status
+		}
+		return strings.Join(oo[i].Repos, ",") < strings.Join(oo[j].Repos, ",")
+	})
+	if oo == nil {
+		oo = []*OwnerStatus{}
+	}
+	return &InspectOutput{Organization: org, Owners: oo}
+}
+
+// writeInspectOutput writes the output in the format except outputLog.
+func writeInspectOutput(w io.Writer, format string, out *InspectOutput) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return errors.Wrap(enc.Encode(out), "json.Encoder.Encode")
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(out); err != nil {
+			return errors.Wrap(err, "yaml.Encoder.Encode")
+		}
+		return errors.Wrap(enc.Close(), "yaml.Encoder.Close")
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"name", "kind", "status", "detail", "members", "last_seen", "repos", "lines"})
+		for _, o := range out.Owners {
+			_ = cw.Write([]string{o.Name, o.Kind, o.Status, o.Detail, strings.Join(o.Members, " "), o.LastSeen, strings.Join(o.Repos, " "), strings.Join(o.Lines, " ")})
+		}
+		cw.Flush()
+		return errors.Wrap(cw.Error(), "csv.Writer.Flush")
+	case outputMarkdown:
+		fmt.Fprintf(w, "|name|kind|status|detail|repos|lines|%s|-|-|-|-|-|-|%s", sep, sep)
+		for _, o := range out.Owners {
+			lines := make([]string, len(o.Occurrences))
+			for i, oc := range o.Occurrences {
+				lines[i] = fmt.Sprintf("[%s](%s)", o.Lines[i], oc.Permalink)
+			}
+			fmt.Fprintf(w, "|%s|%s|%s|%s|%s|%s|%s", markdownCell(o.Name), o.Kind, o.Status, markdownCell(o.Detail), markdownCell(strings.Join(o.Repos, ", ")), markdownCell(strings.Join(lines, ", ")), sep)
+		}
+		return nil
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "NAME\tKIND\tSTATUS\tDETAIL\tREPOS%s", sep)
+		for _, o := range out.Owners {
+			detail := o.Detail
+			if detail == "" {
+				detail = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s%s", o.Name, o.Kind, o.Status, detail, strings.Join(o.Repos, ","), sep)
+		}
+		return tw.Flush()
+	}
+	return errors.Errorf("unknown output format %q", format)
+}
+
+// markdownCell escapes pipes not to break the table.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
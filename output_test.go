@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	newRepo := func(name string) *github.Repository {
+		return &github.Repository{
+			Owner: &github.User{Login: github.String("org")},
+			Name:  github.String(name),
+		}
+	}
+	return &Report{
+		Owners: []*Codeowner{
+			{Name: "org/backend-payments", Kind: TeamOwner, OwnRepos: []string{"repo2", "repo1"}, Successor: "org/backend"},
+			{Name: "gone", Kind: UserOwner, OwnRepos: []string{"repo1"}},
+		},
+		Ineffective: []*Codeowner{
+			{Name: "a", Kind: UserOwner, OwnRepos: []string{"repo2"}},
+		},
+		External: []*Codeowner{
+			{Name: "other/team", Kind: TeamOwner, OwnRepos: []string{"repo1"}},
+		},
+		WeakTeams: []*WeakTeam{
+			{Name: "org/solo", Issue: SingleMemberTeam, Members: []string{"a"}, OwnRepos: []string{"repo1"}},
+		},
+		Stale: []*StaleOwner{
+			{Name: "a", Repo: "repo1", LastSeen: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Reason: "no commit or review since 2022-06-01"},
+		},
+		Repos: []*RepoCodeowners{
+			{Repo: newRepo("repo1"), Path: ".github/CODEOWNERS", Ref: "main", SHA: "abc", File: Parse("* @a @gone\n/pay/ @org/backend-payments @org/solo\n/ext/ @other/team\n")},
+			{Repo: newRepo("repo2"), Path: "CODEOWNERS", Ref: "update-codeowners", File: Parse("# @a\n/pay/ @org/backend-payments\n* @A\n")},
+		},
+	}
+}
+
+func Test_newInspectOutput(t *testing.T) {
+	got := newInspectOutput("org", "https://github.com", testReport())
+
+	occurrence := func(repo, path, ref string, line int, pattern, permalink string) *Occurrence {
+		return &Occurrence{Repo: repo, Path: path, Ref: ref, Line: line, Pattern: pattern, Permalink: permalink}
+	}
+	assert.Equal(t, &InspectOutput{
+		Organization: "org",
+		Owners: []*OwnerStatus{
+			{
+				Name: "a", Kind: "user", Status: statusIneffective, Detail: "no write access", Repos: []string{"repo2"},
+				Lines:       []string{"repo2:CODEOWNERS:3"},
+				Occurrences: []*Occurrence{occurrence("repo2", "CODEOWNERS", "update-codeowners", 3, "*", "https://github.com/org/repo2/blob/update-codeowners/CODEOWNERS#L3")},
+			},
+			{
+				Name: "a", Kind: "user", Status: statusStale, Detail: "no commit or review since 2022-06-01", LastSeen: "2021-03-01", Repos: []string{"repo1"},
+				Lines:       []string{"repo1:.github/CODEOWNERS:1"},
+				Occurrences: []*Occurrence{occurrence("repo1", ".github/CODEOWNERS", "main", 1, "*", "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L1")},
+			},
+			{
+				Name: "gone", Kind: "user", Status: statusMissing, Repos: []string{"repo1"},
+				Lines:       []string{"repo1:.github/CODEOWNERS:1"},
+				Occurrences: []*Occurrence{occurrence("repo1", ".github/CODEOWNERS", "main", 1, "*", "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L1")},
+			},
+			{
+				Name: "org/backend-payments", Kind: "team", Status: statusMissing, Detail: "possibly absorbed into @org/backend (guessed by name)", Repos: []string{"repo1", "repo2"},
+				Lines: []string{"repo1:.github/CODEOWNERS:2", "repo2:CODEOWNERS:2"},
+				Occurrences: []*Occurrence{
+					occurrence("repo1", ".github/CODEOWNERS", "main", 2, "/pay/", "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L2"),
+					occurrence("repo2", "CODEOWNERS", "update-codeowners", 2, "/pay/", "https://github.com/org/repo2/blob/update-codeowners/CODEOWNERS#L2"),
+				},
+			},
+			{
+				Name: "org/solo", Kind: "team", Status: statusWeak, Detail: "single member", Members: []string{"a"}, Repos: []string{"repo1"},
+				Lines:       []string{"repo1:.github/CODEOWNERS:2"},
+				Occurrences: []*Occurrence{occurrence("repo1", ".github/CODEOWNERS", "main", 2, "/pay/", "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L2")},
+			},
+			{
+				Name: "other/team", Kind: "team", Status: statusExternal, Detail: "team of another organization", Repos: []string{"repo1"},
+				Lines:       []string{"repo1:.github/CODEOWNERS:3"},
+				Occurrences: []*Occurrence{occurrence("repo1", ".github/CODEOWNERS", "main", 3, "/ext/", "https://github.com/org/repo1/blob/abc/.github/CODEOWNERS#L3")},
+			},
+		},
+	}, got)
+}
+
+func Test_writeInspectOutput(t *testing.T) {
+	out := &InspectOutput{
+		Organization: "org",
+		Owners: []*OwnerStatus{
+			{
+				Name: "gone", Kind: "user", Status: statusMissing, Repos: []string{"repo1"}, Lines: []string{"repo1:CODEOWNERS:1"},
+				Occurrences: []*Occurrence{{Repo: "repo1", Path: "CODEOWNERS", Ref: "main", Line: 1, Pattern: "*", Permalink: "https://github.com/org/repo1/blob/abc/CODEOWNERS#L1"}},
+			},
+			{Name: "org/solo", Kind: "team", Status: statusWeak, Detail: "single member", Members: []string{"a"}, Repos: []string{"repo1"}, Lines: []string{}, Occurrences: []*Occurrence{}},
+		},
+	}
+
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: outputJSON,
+			expected: `{
+  "organization": "org",
+  "owners": [
+    {
+      "name": "gone",
+      "kind": "user",
+      "status": "missing",
+      "repos": [
+        "repo1"
+      ],
+      "lines": [
+        "repo1:CODEOWNERS:1"
+      ],
+      "occurrences": [
+        {
+          "repo": "repo1",
+          "path": "CODEOWNERS",
+          "ref": "main",
+          "line": 1,
+          "pattern": "*",
+          "permalink": "https://github.com/org/repo1/blob/abc/CODEOWNERS#L1"
+        }
+      ]
+    },
+    {
+      "name": "org/solo",
+      "kind": "team",
+      "status": "weak",
+      "detail": "single member",
+      "members": [
+        "a"
+      ],
+      "repos": [
+        "repo1"
+      ],
+      "lines": [],
+      "occurrences": []
+    }
+  ]
+}
+`,
+		},
+		{
+			format: outputYAML,
+			expected: `organization: org
+owners:
+  - name: gone
+    kind: user
+    status: missing
+    repos:
+      - repo1
+    lines:
+      - repo1:CODEOWNERS:1
+    occurrences:
+      - repo: repo1
+        path: CODEOWNERS
+        ref: main
+        line: 1
+        pattern: '*'
+        permalink: https://github.com/org/repo1/blob/abc/CODEOWNERS#L1
+  - name: org/solo
+    kind: team
+    status: weak
+    detail: single member
+    members:
+      - a
+    repos:
+      - repo1
+    lines: []
+    occurrences: []
+`,
+		},
+		{
+			format: outputCSV,
+			expected: `name,kind,status,detail,members,last_seen,repos,lines
+gone,user,missing,,,,repo1,repo1:CODEOWNERS:1
+org/solo,team,weak,single member,a,,repo1,
+`,
+		},
+		{
+			format: outputMarkdown,
+			expected: `|name|kind|status|detail|repos|lines|
+|-|-|-|-|-|-|
+|gone|user|missing||repo1|[repo1:CODEOWNERS:1](https://github.com/org/repo1/blob/abc/CODEOWNERS#L1)|
+|org/solo|team|weak|single member|repo1||
+`,
+		},
+		{
+			format: outputTable,
+			expected: `NAME      KIND  STATUS   DETAIL         REPOS
+gone      user  missing  -              repo1
+org/solo  team  weak     single member  repo1
+`,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			var b bytes.Buffer
+			err := writeInspectOutput(&b, tc.format, out)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, b.String())
+		})
+	}
+
+	t.Run("empty json", func(t *testing.T) {
+		var b bytes.Buffer
+		err := writeInspectOutput(&b, outputJSON, newInspectOutput("org", "https://github.com", &Report{}))
+
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"organization\": \"org\",\n  \"owners\": []\n}\n", b.String())
+	})
+}
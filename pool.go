@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PoolOptions controls how repositories are processed concurrently.
+type PoolOptions struct {
+	// Concurrency is the number of workers. It's 1 if not positive.
+	Concurrency int
+	// ContinueOnError keeps processing the rest when one fails and returns
+	// all errors at the end. Otherwise the first error cancels the rest.
+	ContinueOnError bool
+}
+
+// runPool calls work for every index in [0, n) by workers, and calls emit
+// with results in index order as soon as all preceding ones are emitted.
+// Results of failed work are not emitted. Unless ContinueOnError, results
+// after the first failed index are not emitted either, but ones before it are
+// still emitted when their work succeeds.
+func runPool[T any](ctx context.Context, n int, opt PoolOptions, work func(ctx context.Context, i int) (T, error), emit func(i int, v T)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opt.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	type result struct {
+		i   int
+		v   T
+		err error
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	go func() {
+		defer close(jobs)
+		for i := 0; i < n; i++ {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				v, err := work(ctx, i)
+				results <- result{i: i, v: v, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		firstErr error
+		failed   int
+		errs     multiError
+		next     int
+	)
+	pending := make(map[int]result)
+	for r := range results {
+		if r.err != nil && firstErr == nil && !opt.ContinueOnError {
+			firstErr = r.err
+			failed = r.i
+			cancel()
+		}
+		pending[r.i] = r
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if p.err != nil {
+				errs = append(errs, p.err)
+				continue
+			}
+			if emit != nil && (firstErr == nil || p.i < failed) {
+				emit(p.i, p.v)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if next < n {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// multiError is a list of errors occurred while processing each item.
+type multiError []error
+
+func (e multiError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	ss := make([]string, len(e))
+	for i, err := range e {
+		ss[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(e), strings.Join(ss, "; "))
+}
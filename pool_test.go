@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runPool(t *testing.T) {
+	t.Run("emit in order", func(t *testing.T) {
+		var got []int
+		err := runPool(context.Background(), 20, PoolOptions{Concurrency: 4}, func(ctx context.Context, i int) (int, error) {
+			// later ones finish earlier
+			time.Sleep(time.Duration(20-i) * time.Millisecond)
+			return i * i, nil
+		}, func(i int, v int) {
+			got = append(got, v)
+		})
+
+		require.NoError(t, err)
+		expected := make([]int, 20)
+		for i := range expected {
+			expected[i] = i * i
+		}
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("bounded workers", func(t *testing.T) {
+		var running, max int32
+		err := runPool(context.Background(), 10, PoolOptions{Concurrency: 3}, func(ctx context.Context, i int) (struct{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return struct{}{}, nil
+		}, nil)
+
+		require.NoError(t, err)
+		assert.True(t, max <= 3, "max running workers: %d", max)
+	})
+
+	t.Run("cancel on first error", func(t *testing.T) {
+		var processed int32
+		var emitted []int
+		err := runPool(context.Background(), 100, PoolOptions{Concurrency: 2}, func(ctx context.Context, i int) (int, error) {
+			atomic.AddInt32(&processed, 1)
+			if i == 3 {
+				return 0, fmt.Errorf("failed %d", i)
+			}
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+			return i, nil
+		}, func(i int, v int) {
+			emitted = append(emitted, v)
+		})
+
+		require.Error(t, err)
+		assert.Equal(t, "failed 3", err.Error())
+		assert.True(t, atomic.LoadInt32(&processed) < 100)
+		// nothing is emitted after the error
+		require.True(t, len(emitted) <= 3)
+		assert.Equal(t, []int{0, 1, 2}[:len(emitted)], emitted)
+	})
+
+	t.Run("emit preceding results after error", func(t *testing.T) {
+		done := make(chan struct{})
+		var emitted []int
+		err := runPool(context.Background(), 3, PoolOptions{Concurrency: 2}, func(ctx context.Context, i int) (int, error) {
+			switch i {
+			case 0:
+				// finishes after 1 fails
+				<-done
+				return i, nil
+			case 1:
+				defer close(done)
+				return 0, fmt.Errorf("failed %d", i)
+			}
+			return i, nil
+		}, func(i int, v int) {
+			emitted = append(emitted, v)
+		})
+
+		require.Error(t, err)
+		assert.Equal(t, "failed 1", err.Error())
+		assert.Equal(t, []int{0}, emitted)
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		var emitted []int
+		err := runPool(context.Background(), 5, PoolOptions{Concurrency: 2, ContinueOnError: true}, func(ctx context.Context, i int) (int, error) {
+			if i%2 == 1 {
+				return 0, fmt.Errorf("failed %d", i)
+			}
+			return i, nil
+		}, func(i int, v int) {
+			emitted = append(emitted, v)
+		})
+
+		require.Error(t, err)
+		assert.Equal(t, "2 errors occurred: failed 1; failed 3", err.Error())
+		assert.Equal(t, []int{0, 2, 4}, emitted)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := runPool(ctx, 5, PoolOptions{}, func(ctx context.Context, i int) (int, error) {
+			return i, nil
+		}, nil)
+
+		assert.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		err := runPool(context.Background(), 0, PoolOptions{Concurrency: 4}, func(ctx context.Context, i int) (int, error) {
+			t.Error("should not reach here")
+			return 0, nil
+		}, nil)
+
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+	headerRateResource  = "X-RateLimit-Resource"
+	headerRetryAfter    = "Retry-After"
+
+	// GitHub recommends to wait at least a second between mutating requests
+	// to avoid secondary rate limit.
+	defaultMutationInterval = time.Second
+
+	defaultMaxAttempts = 5
+	defaultBaseDelay   = time.Second
+	defaultMaxDelay    = 5 * time.Minute
+	// GitHub recommends to wait at least a minute when secondary rate limit
+	// is exceeded without Retry-After header.
+	defaultSecondaryDelay = time.Minute
+)
+
+// RetryOptions controls retries of GitHub API calls.
+type RetryOptions struct {
+	// MaxAttempts is the number of attempts including the first one. It's
+	// defaultMaxAttempts if not positive.
+	MaxAttempts int
+}
+
+// resources of primary rate limit, which are counted separately
+const (
+	resourceCore       = "core"
+	resourceSearch     = "search"
+	resourceCodeSearch = "code_search"
+	resourceGraphQL    = "graphql"
+)
+
+// rateLimitTransport shares rate limit between concurrent requests and
+// retries requests failed by rate limit or server errors. Every request waits
+// while the primary rate limit of its resource is exhausted, and mutating
+// requests are paced not to exceed the secondary rate limit.
+//
+// go-github refuses requests by itself while it knows the limit is exhausted,
+// so the reset time is taken from responses to keep them waiting here.
+type rateLimitTransport struct {
+	base             http.RoundTripper
+	mutationInterval time.Duration
+	maxAttempts      int
+	baseDelay        time.Duration
+	maxDelay         time.Duration
+	secondaryDelay   time.Duration
+
+	mu sync.Mutex
+	// resumeAt pauses every request by secondary rate limit.
+	resumeAt time.Time
+	// resourceResumeAt pauses requests of each exhausted resource.
+	resourceResumeAt map[string]time.Time
+	nextMutation     time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, opt RetryOptions) *rateLimitTransport {
+	maxAttempts := opt.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	return &rateLimitTransport{
+		base:             base,
+		mutationInterval: defaultMutationInterval,
+		maxAttempts:      maxAttempts,
+		baseDelay:        defaultBaseDelay,
+		maxDelay:         defaultMaxDelay,
+		secondaryDelay:   defaultSecondaryDelay,
+		resourceResumeAt: make(map[string]time.Time),
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	resource := requestResource(req)
+	for attempt := 1; ; attempt++ {
+		if err := sleepUntil(ctx, t.reserve(req.Method, resource)); err != nil {
+			return nil, err
+		}
+
+		r, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		res, err := t.base.RoundTrip(r)
+		if err != nil {
+			// only idempotent request is retried since it may have been sent
+			if ctx.Err() != nil || attempt >= t.maxAttempts || !isIdempotent(req.Method) || !canRewind(req) || !isTemporary(err) {
+				return nil, err
+			}
+			if err := sleepUntil(ctx, time.Now().Add(t.backoff(attempt))); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		t.update(resource, res)
+
+		delay, retry := t.retryDelay(req.Method, res, attempt)
+		if !retry || attempt >= t.maxAttempts || !canRewind(req) {
+			return res, nil
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if err := sleepUntil(ctx, time.Now().Add(delay)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reserve returns time when the request of the resource is allowed.
+func (t *rateLimitTransport) reserve(method, resource string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at := t.resumeAt
+	if r := t.resourceResumeAt[resource]; r.After(at) {
+		at = r
+	}
+	if !isMutation(method) {
+		return at
+	}
+	if t.nextMutation.After(at) {
+		at = t.nextMutation
+	}
+	now := time.Now()
+	if now.After(at) {
+		at = now
+	}
+	t.nextMutation = at.Add(t.mutationInterval)
+	return at
+}
+
+// update pauses requests of the resource until reset if its rate limit is
+// exhausted. The reset time is removed from the response so that go-github
+// doesn't refuse later requests before they reach here.
+func (t *rateLimitTransport) update(resource string, res *http.Response) {
+	if res.Header.Get(headerRateRemaining) != "0" {
+		return
+	}
+	reset, err := strconv.ParseInt(res.Header.Get(headerRateReset), 10, 64)
+	if err != nil {
+		return
+	}
+	res.Header.Del(headerRateReset)
+
+	until := time.Unix(reset, 0)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// the resource of the response is preferred, and the one guessed from
+	// the request is paused too in case they differ
+	for _, r := range []string{res.Header.Get(headerRateResource), resource} {
+		if r != "" && until.After(t.resourceResumeAt[r]) {
+			t.resourceResumeAt[r] = until
+		}
+	}
+}
+
+// requestResource guesses the resource of primary rate limit which the
+// request counts against.
+func requestResource(req *http.Request) string {
+	p := strings.TrimPrefix(req.URL.Path, "/api/v3")
+	switch {
+	case strings.HasPrefix(p, "/search/code"):
+		return resourceCodeSearch
+	case strings.HasPrefix(p, "/search/"):
+		return resourceSearch
+	case strings.HasSuffix(p, "/graphql"):
+		return resourceGraphQL
+	}
+	return resourceCore
+}
+
+func (t *rateLimitTransport) pause(until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.resumeAt) {
+		t.resumeAt = until
+	}
+}
+
+// retryDelay reports whether the response to the request of the method should
+// be retried and how long to wait before it in addition to the shared pause.
+// Requests refused by rate limit are retried regardless of the method, but
+// only idempotent ones are retried on server errors since they may have been
+// applied.
+func (t *rateLimitTransport) retryDelay(method string, res *http.Response, attempt int) (time.Duration, bool) {
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if s := res.Header.Get(headerRetryAfter); s != "" {
+			if sec, err := strconv.Atoi(s); err == nil {
+				d := time.Duration(sec) * time.Second
+				// other workers would hit the same limit
+				t.pause(time.Now().Add(d))
+				return d, true
+			}
+		}
+		// waits until reset of the resource by update
+		if res.Header.Get(headerRateRemaining) == "0" {
+			return 0, true
+		}
+		if isSecondaryRateLimit(res) {
+			d := t.backoff(attempt)
+			if d < t.secondaryDelay {
+				d = t.secondaryDelay
+			}
+			t.pause(time.Now().Add(d))
+			return d, true
+		}
+		return 0, false
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !isIdempotent(method) {
+			return 0, false
+		}
+		return t.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns exponential delay of the attempt with jitter.
+func (t *rateLimitTransport) backoff(attempt int) time.Duration {
+	d := t.baseDelay << (attempt - 1)
+	if d <= 0 || d > t.maxDelay {
+		d = t.maxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isSecondaryRateLimit reports whether the response is of secondary rate
+// limit, formerly known as abuse rate limit. Body is kept readable.
+func isSecondaryRateLimit(res *http.Response) bool {
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return false
+	}
+	s := strings.ToLower(string(b))
+	return strings.Contains(s, "secondary rate limit") || strings.Contains(s, "abuse")
+}
+
+// rewind returns request to send in the attempt with a fresh body.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 {
+		return req, nil
+	}
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// isTemporary reports whether the network error would be gone by retry.
+func isTemporary(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// sleepUntil waits until t or ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimitTransport(maxAttempts int) *rateLimitTransport {
+	tr := newRateLimitTransport(http.DefaultTransport, RetryOptions{MaxAttempts: maxAttempts})
+	tr.mutationInterval = 0
+	tr.baseDelay = time.Millisecond
+	tr.maxDelay = 10 * time.Millisecond
+	tr.secondaryDelay = 10 * time.Millisecond
+	return tr
+}
+
+func Test_rateLimitTransport_retry(t *testing.T) {
+	cases := []struct {
+		name            string
+		method          string
+		respond         func(rw http.ResponseWriter, attempt int)
+		maxAttempts     int
+		expectedStatus  int
+		expectedAttempt int
+	}{
+		{
+			name:   "server error",
+			method: http.MethodGet,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				if attempt < 3 {
+					rw.WriteHeader(http.StatusBadGateway)
+				}
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusOK,
+			expectedAttempt: 3,
+		},
+		{
+			name:   "server error of mutation",
+			method: http.MethodPost,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				rw.WriteHeader(http.StatusBadGateway)
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusBadGateway,
+			expectedAttempt: 1,
+		},
+		{
+			name:   "max attempts",
+			method: http.MethodGet,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			},
+			maxAttempts:     3,
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedAttempt: 3,
+		},
+		{
+			name:   "retry after",
+			method: http.MethodPost,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				if attempt == 1 {
+					rw.Header().Set(headerRetryAfter, "0")
+					rw.WriteHeader(http.StatusForbidden)
+				}
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusOK,
+			expectedAttempt: 2,
+		},
+		{
+			name:   "secondary rate limit",
+			method: http.MethodPost,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				if attempt == 1 {
+					rw.WriteHeader(http.StatusForbidden)
+					_, _ = io.WriteString(rw, `{"message": "You have exceeded a secondary rate limit."}`)
+				}
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusOK,
+			expectedAttempt: 2,
+		},
+		{
+			name:   "primary rate limit",
+			method: http.MethodGet,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				if attempt == 1 {
+					rw.Header().Set(headerRateRemaining, "0")
+					rw.Header().Set(headerRateReset, fmt.Sprint(time.Now().Unix()))
+					rw.WriteHeader(http.StatusForbidden)
+				}
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusOK,
+			expectedAttempt: 2,
+		},
+		{
+			name:   "forbidden",
+			method: http.MethodGet,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				rw.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(rw, `{"message": "Resource not accessible by integration"}`)
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusForbidden,
+			expectedAttempt: 1,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			respond: func(rw http.ResponseWriter, attempt int) {
+				rw.WriteHeader(http.StatusNotFound)
+			},
+			maxAttempts:     5,
+			expectedStatus:  http.StatusNotFound,
+			expectedAttempt: 1,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			attempt := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				attempt++
+				if r.Method == http.MethodPost {
+					b, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					assert.Equal(t, `{"a":"b"}`, string(b))
+				}
+				tc.respond(rw, attempt)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(tc.method, server.URL, nil)
+			if tc.method == http.MethodPost {
+				req, err = http.NewRequest(tc.method, server.URL, strings.NewReader(`{"a":"b"}`))
+			}
+			require.NoError(t, err)
+
+			res, err := newTestRateLimitTransport(tc.maxAttempts).RoundTrip(req)
+
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, tc.expectedStatus, res.StatusCode)
+			assert.Equal(t, tc.expectedAttempt, attempt)
+		})
+	}
+}
+
+func Test_rateLimitTransport(t *testing.T) {
+	t.Run("wait until reset when exhausted", func(t *testing.T) {
+		reset := time.Now().Add(1500 * time.Millisecond).Truncate(time.Second).Add(time.Second)
+		var calls []time.Time
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			calls = append(calls, time.Now())
+			if len(calls) == 1 {
+				rw.Header().Set(headerRateRemaining, "0")
+				rw.Header().Set(headerRateReset, fmt.Sprint(reset.Unix()))
+			}
+		}))
+		defer server.Close()
+
+		cli := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, RetryOptions{})}
+		for i := 0; i < 2; i++ {
+			res, err := cli.Get(server.URL)
+			require.NoError(t, err)
+			res.Body.Close()
+		}
+
+		require.Len(t, calls, 2)
+		assert.False(t, calls[1].Before(reset), "second call at %s before reset %s", calls[1], reset)
+	})
+
+	t.Run("wait until reset through github client", func(t *testing.T) {
+		reset := time.Now().Add(1500 * time.Millisecond).Truncate(time.Second).Add(time.Second)
+		var calls []time.Time
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.URL.Path != "/api/v3/repos/org/repo" {
+				t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+				return
+			}
+			calls = append(calls, time.Now())
+			if len(calls) == 1 {
+				rw.Header().Set(headerRateRemaining, "0")
+				rw.Header().Set(headerRateReset, fmt.Sprint(reset.Unix()))
+				rw.Header().Set(headerRateResource, resourceCore)
+			}
+			_, _ = rw.Write([]byte(`{"name":"repo"}`))
+		}))
+		defer server.Close()
+
+		httpClient := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, RetryOptions{})}
+		cli, err := github.NewEnterpriseClient(server.URL, server.URL, httpClient)
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			_, _, err := cli.Repositories.Get(context.Background(), "org", "repo")
+			require.NoError(t, err)
+		}
+
+		require.Len(t, calls, 2)
+		assert.False(t, calls[1].Before(reset), "second call at %s before reset %s", calls[1], reset)
+	})
+
+	t.Run("exhausted resource doesn't pause others", func(t *testing.T) {
+		reset := time.Now().Add(time.Hour)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/api/v3/search/users":
+				rw.Header().Set(headerRateRemaining, "0")
+				rw.Header().Set(headerRateReset, fmt.Sprint(reset.Unix()))
+				rw.Header().Set(headerRateResource, resourceSearch)
+				_, _ = rw.Write([]byte(`{"total_count":0,"items":[]}`))
+			case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/org/repo":
+				_, _ = rw.Write([]byte(`{"name":"repo"}`))
+			default:
+				t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			}
+		}))
+		defer server.Close()
+
+		httpClient := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, RetryOptions{})}
+		cli, err := github.NewEnterpriseClient(server.URL, server.URL, httpClient)
+		require.NoError(t, err)
+		_, _, err = cli.Search.Users(context.Background(), "a", nil)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, _, err = cli.Repositories.Get(ctx, "org", "repo")
+		require.NoError(t, err)
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, _, err = cli.Search.Users(ctx, "a", nil)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("pace mutations", func(t *testing.T) {
+		tr := newRateLimitTransport(http.DefaultTransport, RetryOptions{})
+		tr.mutationInterval = 50 * time.Millisecond
+
+		first := tr.reserve(http.MethodPost, resourceCore)
+		second := tr.reserve(http.MethodPut, resourceCore)
+		read := tr.reserve(http.MethodGet, resourceCore)
+
+		assert.Equal(t, 50*time.Millisecond, second.Sub(first))
+		assert.True(t, read.IsZero())
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		tr := newRateLimitTransport(http.DefaultTransport, RetryOptions{})
+		tr.resumeAt = time.Now().Add(time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost", nil)
+		require.NoError(t, err)
+
+		_, err = tr.RoundTrip(req)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -13,57 +18,155 @@ const (
 
 // ReplaceAll returns the string s of old replaced by new in multilines content.
 func ReplaceAll(s, old, new string) string {
-	ss := strings.Split(s, sep)
-	ll := make([]string, len(ss))
-	for i, l := range ss {
-		ll[i] = Replace(l, old, new)
-	}
-
-	return strings.Join(ll, sep)
+	f := Parse(s)
+	f.Replace(old, new)
+	return f.String()
 }
 
 // Replace returns the string s of old replaced by new in a single line.
 func Replace(s, old, new string) string {
-	if strings.HasPrefix(s, commentPrefix) {
-		return s
-	}
-	if !strings.Contains(s, mentionPrefix) {
-		return s
+	return ReplaceAll(s, old, new)
+}
+
+// Replacement replaces owner Old by New. Owner Old is removed if New is empty.
+type Replacement struct {
+	Old string
+	New string
+}
+
+func (r Replacement) String() string {
+	if r.New == "" {
+		return fmt.Sprintf("Remove %s", r.Old)
 	}
+	return fmt.Sprintf("Update %s to %s", r.Old, r.New)
+}
+
+// Replace replaces owner old by new in every rule and reports whether any
+// rule is changed. Owner old is removed if new is empty.
+func (f *File) Replace(old, new string) bool {
+	return len(f.ReplaceAll([]Replacement{{Old: old, New: new}})) > 0
+}
 
-	old = strings.ToLower(old)
-	if !strings.Contains(strings.ToLower(s), old) {
-		return s
+// ReplaceAll applies all replacements at once in every rule so that an owner
+// is replaced at most once. It returns replacements which changed any rule in
+// given order.
+func (f *File) ReplaceAll(rr []Replacement) []Replacement {
+	idx := make(map[string]int, len(rr))
+	for i, r := range rr {
+		idx[strings.ToLower(r.Old)] = i
 	}
 
-	m := make(map[string]struct{})
-	cc := strings.Split(s, mentionPrefix)
-	stack, cands := []string{cc[0]}, cc[1:]
-	for _, name := range cands {
-		// identifier
-		n := strings.TrimSpace(strings.ToLower(name))
-
-		// non target first seen owner
-		if _, ok := m[n]; n != old && !ok {
-			m[n] = struct{}{}
-			stack = append(stack, name)
+	applied := make([]bool, len(rr))
+	for _, l := range f.Lines {
+		if l.Rule == nil {
 			continue
 		}
+		if l.Rule.replaceAll(rr, idx, applied) {
+			l.trimTrailingSpace()
+		}
+	}
 
-		replaced := strings.ReplaceAll(strings.ToLower(name), old, new)
-		n = strings.TrimSpace(strings.ToLower(replaced))
-		if n == "" {
-			continue
+	result := make([]Replacement, 0, len(rr))
+	for i, r := range rr {
+		if applied[i] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Replace replaces owner old by new case insensitively and reports whether
+// the rule is changed. Duplicated owners are merged keeping the first one.
+func (r *Rule) Replace(old, new string) bool {
+	rr := []Replacement{{Old: old, New: new}}
+	return r.replaceAll(rr, map[string]int{strings.ToLower(old): 0}, make([]bool, 1))
+}
+
+// replaceAll replaces owners by rr looked up by lower cased old owner in idx,
+// and marks applied ones.
+func (r *Rule) replaceAll(rr []Replacement, idx map[string]int, applied []bool) bool {
+	changed := false
+	for _, o := range r.Owners {
+		if _, ok := idx[strings.ToLower(o.Name())]; ok {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false
+	}
+
+	m := make(map[string]struct{}, len(r.Owners))
+	owners := make([]*Owner, 0, len(r.Owners))
+	for _, o := range r.Owners {
+		n := strings.ToLower(o.Name())
+		if i, ok := idx[n]; ok {
+			applied[i] = true
+			if rr[i].New == "" {
+				continue
+			}
+			o = newOwner(rr[i].New, o.Space)
+			n = strings.ToLower(rr[i].New)
 		}
 		if _, ok := m[n]; ok {
 			continue
 		}
-
 		m[n] = struct{}{}
-		stack = append(stack, replaced)
+		owners = append(owners, o)
+	}
+	r.Owners = owners
+	return true
+}
+
+// LoadReplacements reads replacements from YAML mapping file of old owner to
+// new one. Old owner is removed if new one is null or empty.
+//
+//	"@a": "@b"
+//	org/old-team: org/new-team
+//	c: null
+func LoadReplacements(path string) ([]Replacement, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.ReadFile")
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, errors.Wrap(err, "yaml.Unmarshal")
 	}
+	if len(node.Content) == 0 {
+		return nil, errors.Errorf("%s: empty mapping", path)
+	}
+	doc := node.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, errors.Errorf("%s:%d: expected mapping of old owner to new one", path, doc.Line)
+	}
+
+	seen := make(map[string]int, len(doc.Content)/2)
+	rr := make([]Replacement, 0, len(doc.Content)/2)
+	for i := 0; i < len(doc.Content); i += 2 {
+		k, v := doc.Content[i], doc.Content[i+1]
+		if k.Kind != yaml.ScalarNode || v.Kind != yaml.ScalarNode {
+			return nil, errors.Errorf("%s:%d: expected old owner and new one", path, k.Line)
+		}
+		r := Replacement{
+			Old: trimMention(k.Value),
+		}
+		if v.Tag != "!!null" {
+			r.New = trimMention(v.Value)
+		}
+		if r.Old == "" {
+			return nil, errors.Errorf("%s:%d: empty old owner", path, k.Line)
+		}
+		if line, ok := seen[strings.ToLower(r.Old)]; ok {
+			return nil, errors.Errorf("%s:%d: duplicated old owner %q with line %d", path, k.Line, r.Old, line)
+		}
+		seen[strings.ToLower(r.Old)] = k.Line
+		rr = append(rr, r)
+	}
+	return rr, nil
+}
 
-	// remove trailing whitespace
-	stack[len(stack)-1] = strings.TrimSpace(stack[len(stack)-1])
-	return strings.Join(stack, mentionPrefix)
+func trimMention(s string) string {
+	return strings.TrimPrefix(s, mentionPrefix)
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReplaceAll(t *testing.T) {
@@ -35,6 +38,20 @@ func TestReplaceAll(t *testing.T) {
 			new:      "b",
 			expected: "* @b\n# .github @a",
 		},
+		{
+			name:     "keep inline comment",
+			s:        "* @a @b # owners\n",
+			old:      "b",
+			new:      "",
+			expected: "* @a # owners\n",
+		},
+		{
+			name:     "keep crlf",
+			s:        "* @a \r\n/docs @c\r\n",
+			old:      "a",
+			new:      "b",
+			expected: "* @b\r\n/docs @c\r\n",
+		},
 		{
 			name:     "keep whitespace path name",
 			s:        "* @a\n/example\\ path/ @a",
@@ -152,6 +169,27 @@ func TestReplace(t *testing.T) {
 			new:      "",
 			expected: "* @a @c",
 		},
+		{
+			name:     "email to user",
+			s:        "* @a User@example.com",
+			old:      "user@example.com",
+			new:      "b",
+			expected: "* @a @b",
+		},
+		{
+			name:     "user to email",
+			s:        "* @a @b",
+			old:      "a",
+			new:      "a@example.com",
+			expected: "* a@example.com @b",
+		},
+		{
+			name:     "distinguish email with member",
+			s:        "* a@example.com @a",
+			old:      "a",
+			new:      "b",
+			expected: "* a@example.com @b",
+		},
 		{
 			name:     "remove all owner",
 			s:        "* @b",
@@ -169,3 +207,115 @@ func TestReplace(t *testing.T) {
 		})
 	}
 }
+
+func TestFile_ReplaceAll(t *testing.T) {
+	cases := []struct {
+		name            string
+		s               string
+		rr              []Replacement
+		expected        string
+		expectedApplied []Replacement
+	}{
+		{
+			name: "many replacements",
+			s:    "* @a @org/old\n/docs @c @d\n",
+			rr: []Replacement{
+				{Old: "a", New: "b"},
+				{Old: "org/old", New: "org/new"},
+				{Old: "c", New: ""},
+				{Old: "x", New: "y"},
+			},
+			expected: "* @b @org/new\n/docs @d\n",
+			expectedApplied: []Replacement{
+				{Old: "a", New: "b"},
+				{Old: "org/old", New: "org/new"},
+				{Old: "c", New: ""},
+			},
+		},
+		{
+			name: "replace at once",
+			s:    "* @a @b",
+			rr: []Replacement{
+				{Old: "a", New: "b"},
+				{Old: "b", New: "c"},
+			},
+			expected: "* @b @c",
+			expectedApplied: []Replacement{
+				{Old: "a", New: "b"},
+				{Old: "b", New: "c"},
+			},
+		},
+		{
+			name: "nothing applied",
+			s:    "* @a",
+			rr: []Replacement{
+				{Old: "b", New: "c"},
+			},
+			expected:        "* @a",
+			expectedApplied: []Replacement{},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f := Parse(tc.s)
+			applied := f.ReplaceAll(tc.rr)
+
+			assert.Equal(t, tc.expected, f.String())
+			assert.Equal(t, tc.expectedApplied, applied)
+		})
+	}
+}
+
+func TestLoadReplacements(t *testing.T) {
+	cases := []struct {
+		name        string
+		given       string
+		expected    []Replacement
+		expectedErr string
+	}{
+		{
+			name:  "mapping",
+			given: "\"@a\": \"@b\"\norg/old: org/new\nc: null\nd: \"\"\ne:\n",
+			expected: []Replacement{
+				{Old: "a", New: "b"},
+				{Old: "org/old", New: "org/new"},
+				{Old: "c", New: ""},
+				{Old: "d", New: ""},
+				{Old: "e", New: ""},
+			},
+		},
+		{
+			name:        "not a mapping",
+			given:       "- a\n- b\n",
+			expectedErr: "expected mapping",
+		},
+		{
+			name:        "duplicated case insensitive",
+			given:       "a: b\nA: c\n",
+			expectedErr: "duplicated old owner",
+		},
+		{
+			name:        "empty file",
+			given:       "",
+			expectedErr: "empty mapping",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mapping.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.given), 0o600))
+
+			got, err := LoadReplacements(path)
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
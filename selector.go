@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/pkg/errors"
+)
+
+// visibilities of repositories to select
+const (
+	visibilityPublic   = "public"
+	visibilityPrivate  = "private"
+	visibilityInternal = "internal"
+	visibilityAll      = "all"
+)
+
+var visibilities = []string{visibilityPublic, visibilityPrivate, visibilityInternal, visibilityAll}
+
+// modes selecting forks and templates
+const (
+	includeMode = "include"
+	excludeMode = "exclude"
+	onlyMode    = "only"
+)
+
+var selectModes = []string{includeMode, excludeMode, onlyMode}
+
+// RepoSelector selects repositories of an owner to process. Archived ones
+// are never selected. Zero value selects every private repository of an
+// organization, or every repository of a user account.
+type RepoSelector struct {
+	// Visibility is one of public, private, internal and all. It's private
+	// for organization and all for user account if empty.
+	Visibility string
+	// Include and Exclude are glob patterns of names. Repository is
+	// selected if it matches any of Include, or Include is empty, and
+	// matches none of Exclude. The same goes for IncludeRegexp and
+	// ExcludeRegexp.
+	Include       []string
+	Exclude       []string
+	IncludeRegexp []*regexp.Regexp
+	ExcludeRegexp []*regexp.Regexp
+	// Topics and Languages select repositories having any of them if not
+	// empty. Languages are compared case-insensitively.
+	Topics    []string
+	Languages []string
+	// Forks and Templates are one of include, exclude and only. They're
+	// include if empty.
+	Forks     string
+	Templates string
+	// PushedAfter selects repositories pushed after the time if not zero.
+	PushedAfter time.Time
+	// Names selects only repositories of the names if not empty. Name
+	// written as "owner/name" selects only the repository of the owner.
+	Names []string
+}
+
+// Validate checks visibility, modes and patterns.
+func (s *RepoSelector) Validate() error {
+	if s.Visibility != "" && !contains(visibilities, s.Visibility) {
+		return errors.Errorf("invalid visibility %q, expected one of %s", s.Visibility, strings.Join(visibilities, ", "))
+	}
+	for _, m := range []string{s.Forks, s.Templates} {
+		if m != "" && !contains(selectModes, m) {
+			return errors.Errorf("invalid mode %q, expected one of %s", m, strings.Join(selectModes, ", "))
+		}
+	}
+	for _, p := range append(append([]string{}, s.Include...), s.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return errors.Wrapf(err, "invalid glob %q", p)
+		}
+	}
+	return nil
+}
+
+// listType returns type of repositories to list by GitHub API.
+func (s *RepoSelector) listType() string {
+	if s == nil || s.Visibility == "" {
+		return visibilityPrivate
+	}
+	return s.Visibility
+}
+
+// matchVisibility reports whether the repository of user account has the
+// visibility.
+func (s *RepoSelector) matchVisibility(r *github.Repository) bool {
+	if s == nil || s.Visibility == "" || s.Visibility == visibilityAll {
+		return true
+	}
+	v := r.GetVisibility()
+	if v == "" {
+		v = visibilityPublic
+		if r.GetPrivate() {
+			v = visibilityPrivate
+		}
+	}
+	return v == s.Visibility
+}
+
+// Match reports whether the repository is selected. Visibility is not
+// checked since it's filtered when listed.
+func (s *RepoSelector) Match(r *github.Repository) bool {
+	if r.GetArchived() {
+		return false
+	}
+	if s == nil {
+		return true
+	}
+	name := r.GetName()
+	if len(s.Names) > 0 && !matchRepoName(s.Names, r) {
+		return false
+	}
+	if len(s.Include) > 0 && !matchAnyGlob(s.Include, name) {
+		return false
+	}
+	if matchAnyGlob(s.Exclude, name) {
+		return false
+	}
+	if len(s.IncludeRegexp) > 0 && !matchAnyRegexp(s.IncludeRegexp, name) {
+		return false
+	}
+	if matchAnyRegexp(s.ExcludeRegexp, name) {
+		return false
+	}
+	if len(s.Topics) > 0 && !containsAnyFold(s.Topics, r.Topics) {
+		return false
+	}
+	if len(s.Languages) > 0 && !containsFold(s.Languages, r.GetLanguage()) {
+		return false
+	}
+	if !matchMode(s.Forks, r.GetFork()) || !matchMode(s.Templates, r.GetIsTemplate()) {
+		return false
+	}
+	if !s.PushedAfter.IsZero() && !r.GetPushedAt().After(s.PushedAfter) {
+		return false
+	}
+	return true
+}
+
+// Filter returns selected repositories in the same order.
+func (s *RepoSelector) Filter(rr []*github.Repository) []*github.Repository {
+	filtered := make([]*github.Repository, 0, len(rr))
+	for _, r := range rr {
+		if s.Match(r) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// matchRepoName reports whether the repository is named by any of names,
+// which are "name" or "owner/name".
+func matchRepoName(names []string, r *github.Repository) bool {
+	fullName := r.GetOwner().GetLogin() + "/" + r.GetName()
+	for _, n := range names {
+		if strings.Contains(n, "/") {
+			if strings.EqualFold(n, fullName) {
+				return true
+			}
+		} else if strings.EqualFold(n, r.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, p := range patterns {
+		// patterns are validated
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyRegexp(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMode(mode string, v bool) bool {
+	switch mode {
+	case excludeMode:
+		return !v
+	case onlyMode:
+		return v
+	}
+	return true
+}
+
+func containsFold(ss []string, s string) bool {
+	for _, v := range ss {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyFold(ss, vv []string) bool {
+	for _, v := range vv {
+		if containsFold(ss, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRepoNames reads names of repositories from the file, one per line.
+// Blank lines and lines starting with # are ignored. Name may be written as
+// "owner/name" to select the repository of the owner only.
+func LoadRepoNames(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "os.Open")
+	}
+	defer f.Close()
+
+	var names []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		l := strings.TrimSpace(sc.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		names = append(names, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "bufio.Scanner.Scan")
+	}
+	if len(names) == 0 {
+		return nil, errors.Errorf("no repository in %s", name)
+	}
+	return names, nil
+}
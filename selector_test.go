@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoSelector_Match(t *testing.T) {
+	pushed := &github.Timestamp{Time: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)}
+	repo := &github.Repository{
+		Owner:      &github.User{Login: github.String("org")},
+		Name:       github.String("api-server"),
+		Topics:     []string{"backend", "go"},
+		Language:   github.String("Go"),
+		Fork:       github.Bool(false),
+		IsTemplate: github.Bool(false),
+		PushedAt:   pushed,
+	}
+	cases := []struct {
+		name     string
+		given    *RepoSelector
+		repo     *github.Repository
+		expected bool
+	}{
+		{
+			name:     "nil",
+			given:    nil,
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "archived",
+			given:    &RepoSelector{},
+			repo:     &github.Repository{Name: github.String("old"), Archived: github.Bool(true)},
+			expected: false,
+		},
+		{
+			name:     "include glob",
+			given:    &RepoSelector{Include: []string{"web-*", "api-*"}},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "not included glob",
+			given:    &RepoSelector{Include: []string{"web-*"}},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "exclude glob",
+			given:    &RepoSelector{Include: []string{"*"}, Exclude: []string{"*-server"}},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "include regexp",
+			given:    &RepoSelector{IncludeRegexp: []*regexp.Regexp{regexp.MustCompile(`^api-`)}},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "exclude regexp",
+			given:    &RepoSelector{ExcludeRegexp: []*regexp.Regexp{regexp.MustCompile(`server$`)}},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "any topic",
+			given:    &RepoSelector{Topics: []string{"frontend", "Backend"}},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "no topic",
+			given:    &RepoSelector{Topics: []string{"frontend"}},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "language",
+			given:    &RepoSelector{Languages: []string{"go"}},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "other language",
+			given:    &RepoSelector{Languages: []string{"Python"}},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "only forks",
+			given:    &RepoSelector{Forks: onlyMode},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "exclude templates",
+			given:    &RepoSelector{Templates: excludeMode},
+			repo:     &github.Repository{Name: github.String("template"), IsTemplate: github.Bool(true)},
+			expected: false,
+		},
+		{
+			name:     "pushed after",
+			given:    &RepoSelector{PushedAfter: pushed.Add(-time.Hour)},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "not pushed after",
+			given:    &RepoSelector{PushedAfter: pushed.Time},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "names",
+			given:    &RepoSelector{Names: []string{"web", "API-server"}},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "not in names",
+			given:    &RepoSelector{Names: []string{"web"}},
+			repo:     repo,
+			expected: false,
+		},
+		{
+			name:     "full names",
+			given:    &RepoSelector{Names: []string{"web", "Org/api-server"}},
+			repo:     repo,
+			expected: true,
+		},
+		{
+			name:     "full name of another owner",
+			given:    &RepoSelector{Names: []string{"other/api-server"}},
+			repo:     repo,
+			expected: false,
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.given.Match(tc.repo))
+		})
+	}
+}
+
+func TestRepoSelector_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		given *RepoSelector
+		valid bool
+	}{
+		{
+			name:  "zero",
+			given: &RepoSelector{},
+			valid: true,
+		},
+		{
+			name:  "valid",
+			given: &RepoSelector{Visibility: visibilityInternal, Forks: excludeMode, Templates: onlyMode, Include: []string{"a-*"}},
+			valid: true,
+		},
+		{
+			name:  "invalid visibility",
+			given: &RepoSelector{Visibility: "secret"},
+		},
+		{
+			name:  "invalid mode",
+			given: &RepoSelector{Forks: "yes"},
+		},
+		{
+			name:  "invalid glob",
+			given: &RepoSelector{Exclude: []string{"["}},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.given.Validate()
+
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestListActivatedRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/orgs/org/repos" {
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			return
+		}
+		assert.Equal(t, "internal", r.URL.Query().Get("type"))
+		rw.Header().Set("Content-Type", "application/json")
+		_, err := io.WriteString(rw, `[{"name": "a"}, {"name": "b", "archived": true}, {"name": "c", "fork": true}]`)
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+
+	repos, err := ListActivatedRepositories(context.Background(), mockGithubCli, "org", &RepoSelector{Visibility: visibilityInternal, Forks: excludeMode})
+
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "a", repos[0].GetName())
+}
+
+func TestLoadRepoNames(t *testing.T) {
+	t.Run("names", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "repos.txt")
+		require.NoError(t, os.WriteFile(name, []byte("# payments\napi\n\n  org/web  \n"), 0o600))
+
+		got, err := LoadRepoNames(name)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api", "org/web"}, got)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "repos.txt")
+		require.NoError(t, os.WriteFile(name, []byte("# nothing\n"), 0o600))
+
+		_, err := LoadRepoNames(name)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestListActivatedRepositories_user(t *testing.T) {
+	var me string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/orgs/user/repos":
+			rw.WriteHeader(http.StatusNotFound)
+		case "/api/v3/user":
+			if me == "" {
+				rw.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(rw, `{"message": "Resource not accessible by integration"}`)
+				return
+			}
+			_, err := io.WriteString(rw, `{"login": "`+me+`"}`)
+			require.NoError(t, err)
+		case "/api/v3/users/user/repos":
+			assert.Equal(t, "owner", r.URL.Query().Get("type"))
+			_, err := io.WriteString(rw, `[{"name": "a", "visibility": "public"}]`)
+			require.NoError(t, err)
+		case "/api/v3/user/repos":
+			assert.Equal(t, "owner", r.URL.Query().Get("affiliation"))
+			assert.Empty(t, r.URL.Query().Get("type"))
+			_, err := io.WriteString(rw, `[{"name": "a", "visibility": "public"}, {"name": "b", "private": true}]`)
+			require.NoError(t, err)
+		default:
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+		}
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+
+	t.Run("authenticated user", func(t *testing.T) {
+		me = "User"
+
+		repos, err := ListActivatedRepositories(context.Background(), mockGithubCli, "user", nil)
+
+		require.NoError(t, err)
+		assert.Len(t, repos, 2)
+	})
+
+	t.Run("private of authenticated user", func(t *testing.T) {
+		me = "user"
+
+		repos, err := ListActivatedRepositories(context.Background(), mockGithubCli, "user", &RepoSelector{Visibility: visibilityPrivate})
+
+		require.NoError(t, err)
+		require.Len(t, repos, 1)
+		assert.Equal(t, "b", repos[0].GetName())
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		me = "other"
+
+		repos, err := ListActivatedRepositories(context.Background(), mockGithubCli, "user", nil)
+
+		require.NoError(t, err)
+		require.Len(t, repos, 1)
+		assert.Equal(t, "a", repos[0].GetName())
+	})
+
+	t.Run("not authenticated as user", func(t *testing.T) {
+		me = ""
+
+		repos, err := ListActivatedRepositories(context.Background(), mockGithubCli, "user", nil)
+
+		require.NoError(t, err)
+		require.Len(t, repos, 1)
+		assert.Equal(t, "a", repos[0].GetName())
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/pkg/errors"
+)
+
+// defaultStaleWindow is how long an owner can be inactive in a repository.
+const defaultStaleWindow = 180 * 24 * time.Hour
+
+// StaleOwner is a user owner with no commit or review in a repository.
+type StaleOwner struct {
+	Name string
+	Repo string
+	// LastSeen is when the latest commit or review of the owner in the
+	// repository is. It's zero if there is none.
+	LastSeen time.Time
+	Reason   string
+}
+
+// listStaleOwners returns user owners with no commit or review in repositories
+// they own since the time, in order of name and repository. Missing owners
+// are not checked.
+func listStaleOwners(ctx context.Context, cli *github.Client, all []*RepoCodeowners, missing []string, since time.Time, pool PoolOptions) ([]*StaleOwner, error) {
+	skip := make(map[string]struct{}, len(missing))
+	for _, n := range missing {
+		skip[strings.ToLower(n)] = struct{}{}
+	}
+
+	var stale []*StaleOwner
+	err := runPool(ctx, len(all), pool, func(ctx context.Context, i int) ([]*StaleOwner, error) {
+		rc := all[i]
+		var ss []*StaleOwner
+		for _, n := range rc.File.OwnerNames() {
+			if _, ok := skip[strings.ToLower(n)]; ok || ownerKind(n) != UserOwner {
+				continue
+			}
+			s, err := checkStale(ctx, cli, rc.Repo, n, since)
+			if err != nil {
+				return nil, errors.Wrap(err, rc.Repo.GetFullName())
+			}
+			if s != nil {
+				ss = append(ss, s)
+			}
+		}
+		return ss, nil
+	}, func(i int, ss []*StaleOwner) {
+		stale = append(stale, ss...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		if stale[i].Name != stale[j].Name {
+			return stale[i].Name < stale[j].Name
+		}
+		return stale[i].Repo < stale[j].Repo
+	})
+	return stale, nil
+}
+
+// checkStale returns nil if the user has any commit or review in the
+// repository since the time. Otherwise it looks up the latest activity ever.
+func checkStale(ctx context.Context, cli *github.Client, r *github.Repository, login string, since time.Time) (*StaleOwner, error) {
+	at, err := LastCommitAt(ctx, cli, r, login, since)
+	if err != nil {
+		return nil, err
+	}
+	if !at.IsZero() {
+		return nil, nil
+	}
+	at, err = LastReviewAt(ctx, cli, r, login, since)
+	if err != nil {
+		return nil, err
+	}
+	if !at.IsZero() {
+		return nil, nil
+	}
+
+	lastCommit, err := LastCommitAt(ctx, cli, r, login, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	lastReview, err := LastReviewAt(ctx, cli, r, login, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	s := &StaleOwner{
+		Name:     login,
+		Repo:     r.GetName(),
+		LastSeen: lastCommit,
+		Reason:   "no commit or review ever",
+	}
+	if lastReview.After(s.LastSeen) {
+		s.LastSeen = lastReview
+	}
+	if !s.LastSeen.IsZero() {
+		s.Reason = fmt.Sprintf("no commit or review since %s", since.UTC().Format("2006-01-02"))
+	}
+	return s, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_listStaleOwners(t *testing.T) {
+	since := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		var body string
+		switch r.URL.Path {
+		case "/api/v3/repos/org/repo/commits":
+			switch {
+			case q.Get("author") == "committer" && q.Get("since") != "":
+				body = `[{"commit": {"author": {"date": "2022-07-01T00:00:00Z"}}}]`
+			case q.Get("author") == "old" && q.Get("since") == "":
+				body = `[{"commit": {"author": {"date": "2021-01-01T00:00:00Z"}}}]`
+			default:
+				body = `[]`
+			}
+		case "/api/v3/search/issues":
+			switch q.Get("q") {
+			case "repo:org/repo type:pr reviewed-by:reviewer updated:>=2022-06-01":
+				body = `{"total_count": 1, "items": [{"number": 1, "updated_at": "2022-08-01T00:00:00Z"}]}`
+			case "repo:org/repo type:pr reviewed-by:old":
+				body = `{"total_count": 1, "items": [{"number": 2, "updated_at": "2021-03-01T00:00:00Z"}]}`
+			case "repo:org/repo type:pr reviewed-by:touched updated:>=2022-06-01", "repo:org/repo type:pr reviewed-by:touched":
+				body = `{"total_count": 1, "items": [{"number": 3, "updated_at": "2022-08-01T00:00:00Z"}]}`
+			default:
+				body = `{"total_count": 0, "items": []}`
+			}
+		case "/api/v3/repos/org/repo/pulls/1/reviews":
+			body = `[{"user": {"login": "reviewer"}, "submitted_at": "2022-07-15T00:00:00Z"}]`
+		case "/api/v3/repos/org/repo/pulls/2/reviews":
+			body = `[{"user": {"login": "old"}, "submitted_at": "2021-02-20T00:00:00Z"}, {"user": {"login": "other"}, "submitted_at": "2021-03-01T00:00:00Z"}]`
+		case "/api/v3/repos/org/repo/pulls/3/reviews":
+			// reviewed long ago but labeled recently
+			body = `[{"user": {"login": "touched"}, "submitted_at": "2020-05-01T00:00:00Z"}, {"user": {"login": "other"}, "submitted_at": "2022-08-01T00:00:00Z"}]`
+		default:
+			t.Errorf("%s, method: %s, request uri: %s", "should not reach here", r.Method, r.RequestURI)
+			return
+		}
+		_, err := io.WriteString(rw, body)
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	mockGithubCli, err := github.NewEnterpriseClient(server.URL, server.URL, server.Client())
+	require.NoError(t, err)
+	all := []*RepoCodeowners{
+		{
+			Repo: &github.Repository{
+				Owner: &github.User{Login: github.String("org")},
+				Name:  github.String("repo"),
+			},
+			File: Parse("* @committer @reviewer @old @touched @never @org/team a@example.com @missing"),
+		},
+	}
+
+	got, err := listStaleOwners(context.Background(), mockGithubCli, all, []string{"missing"}, since, PoolOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*StaleOwner{
+		{Name: "never", Repo: "repo", Reason: "no commit or review ever"},
+		{Name: "old", Repo: "repo", LastSeen: time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC), Reason: "no commit or review since 2022-06-01"},
+		{Name: "touched", Repo: "repo", LastSeen: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC), Reason: "no commit or review since 2022-06-01"},
+	}, got)
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSuggestWindow     = 180 * 24 * time.Hour
+	defaultSuggestMaxCommits = 300
+	defaultSuggestMaxPulls   = 100
+	defaultSuggestTop        = 3
+)
+
+// SuggestOptions controls how Suggest collects activities.
+type SuggestOptions struct {
+	Pool PoolOptions
+	// Since is the beginning of activities to collect.
+	Since time.Time
+	// MaxCommits and MaxPulls limit the number of the latest commits and
+	// closed pull requests to collect.
+	MaxCommits int
+	MaxPulls   int
+	// Top is the number of candidates per rule.
+	Top int
+}
+
+// Activity is a commit or a review of a user touching files.
+type Activity struct {
+	Login  string
+	Review bool
+	Files  []string
+}
+
+// Candidate is a user or a team suggested to own files matched by a rule.
+type Candidate struct {
+	Name    string
+	Kind    OwnerKind
+	Commits int
+	Reviews int
+	// Share is the ratio of commits and reviews of the candidate among every
+	// one touching files of the rule. Commits and reviews of a team are sum
+	// of its members, but its share is scaled by the ratio of active members,
+	// so that a team doesn't outrank its only active member.
+	Share float64
+	// NoWriteAccess is true if the user lacks write access to the
+	// repository, so GitHub would ignore it as owner.
+	NoWriteAccess bool
+}
+
+// Suggestion is candidates of a rule in descending order of share.
+type Suggestion struct {
+	Rule       *Rule
+	Candidates []*Candidate
+	// Orphan rule has no existing owner.
+	Orphan bool
+	// Unowned rule is not in the file but proposed for files no rule
+	// matches, grouped by top-level directory. Its Line is 0.
+	Unowned bool
+}
+
+// RepoSuggestion is suggestions for every rule of the effective CODEOWNERS
+// file of a repository.
+type RepoSuggestion struct {
+	Repo        *github.Repository
+	Path        string
+	File        *File
+	Suggestions []*Suggestion
+}
+
+// Suggest ranks candidates of every rule of the repository by commits and
+// reviews since opt.Since. Teams having write access to the repository are
+// ranked by their members. Files no rule matches are grouped by top-level
+// directories existing in the default branch.
+func Suggest(ctx context.Context, cli *github.Client, r *github.Repository, opt SuggestOptions) (*RepoSuggestion, error) {
+	content, err := GetCodeownersContentAt(ctx, cli, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	s, err := content.GetContent()
+	if err != nil {
+		return nil, errors.Wrap(err, "content.GetContent")
+	}
+	f := Parse(s)
+
+	acts, err := listActivities(ctx, cli, r, opt)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ListFiles(ctx, cli, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		teams map[string][]string
+		known []string
+	)
+	if r.GetOwner().GetType() == "Organization" {
+		org := r.GetOwner().GetLogin()
+		teams, err = listWritableTeamMembers(ctx, cli, r, opt.Pool)
+		if err != nil {
+			return nil, err
+		}
+		known, err = listMemberNames(ctx, cli, org)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := loadTeamTree(ctx, cli, org)
+		if err != nil {
+			return nil, err
+		}
+		known = append(known, tree.Names()...)
+	}
+
+	ss, err := RankCandidates(f, acts, files, teams, opt.Top)
+	if err != nil {
+		return nil, errors.Wrap(err, content.GetPath())
+	}
+	for _, sg := range ss {
+		sg.Orphan = isOrphan(sg.Rule, known)
+	}
+	if err := checkCandidateAccess(ctx, cli, r, ss, opt.Pool); err != nil {
+		return nil, err
+	}
+	return &RepoSuggestion{
+		Repo:        r,
+		Path:        content.GetPath(),
+		File:        f,
+		Suggestions: ss,
+	}, nil
+}
+
+// checkCandidateAccess marks user candidates without write access. Teams are
+// candidates only if they have write access.
+func checkCandidateAccess(ctx context.Context, cli *github.Client, r *github.Repository, ss []*Suggestion, pool PoolOptions) error {
+	var users []string
+	for _, s := range ss {
+		for _, c := range s.Candidates {
+			if c.Kind == UserOwner {
+				users = append(users, c.Name)
+			}
+		}
+	}
+	users = set(users)
+
+	noAccess := make(map[string]bool, len(users))
+	err := runPool(ctx, len(users), pool, func(ctx context.Context, i int) (bool, error) {
+		ok, err := HasUserWriteAccess(ctx, cli, r, users[i])
+		if err != nil {
+			return false, errors.Wrap(err, users[i])
+		}
+		return ok, nil
+	}, func(i int, ok bool) {
+		noAccess[strings.ToLower(users[i])] = !ok
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range ss {
+		for _, c := range s.Candidates {
+			if c.Kind == UserOwner {
+				c.NoWriteAccess = noAccess[strings.ToLower(c.Name)]
+			}
+		}
+	}
+	return nil
+}
+
+// isOrphan reports whether the rule has no valid owner. Owner is valid only
+// if it's in known unless known is nil.
+func isOrphan(r *Rule, known []string) bool {
+	for _, o := range r.Owners {
+		if o.Kind() == UnknownOwner {
+			continue
+		}
+		// email is hardly resolved to a member
+		if known == nil || o.Kind() == EmailOwner || len(diff([]string{o.Name()}, known)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// listActivities returns authors of commits and reviewers of closed pull
+// requests with files they touched. Bots and commits of unknown users are
+// ignored.
+func listActivities(ctx context.Context, cli *github.Client, r *github.Repository, opt SuggestOptions) ([]*Activity, error) {
+	commits, err := ListCommitsSince(ctx, cli, r, opt.Since, opt.MaxCommits)
+	if err != nil {
+		return nil, err
+	}
+	var acts []*Activity
+	err = runPool(ctx, len(commits), opt.Pool, func(ctx context.Context, i int) (*Activity, error) {
+		login := commits[i].GetAuthor().GetLogin()
+		if login == "" || isBot(login) {
+			return nil, nil
+		}
+		files, err := GetCommitFiles(ctx, cli, r, commits[i].GetSHA())
+		if err != nil {
+			return nil, errors.Wrap(err, commits[i].GetSHA())
+		}
+		return &Activity{Login: login, Files: files}, nil
+	}, func(i int, a *Activity) {
+		if a != nil {
+			acts = append(acts, a)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pulls, err := ListClosedPullRequestsSince(ctx, cli, r, opt.Since, opt.MaxPulls)
+	if err != nil {
+		return nil, err
+	}
+	err = runPool(ctx, len(pulls), opt.Pool, func(ctx context.Context, i int) ([]*Activity, error) {
+		n := pulls[i].GetNumber()
+		reviewers, err := ListReviewers(ctx, cli, r, n)
+		if err != nil {
+			return nil, errors.Wrapf(err, "#%d", n)
+		}
+		reviewers = diff(reviewers, []string{pulls[i].GetUser().GetLogin()})
+		if len(reviewers) == 0 {
+			return nil, nil
+		}
+		files, err := ListPullRequestFiles(ctx, cli, r, n)
+		if err != nil {
+			return nil, errors.Wrapf(err, "#%d", n)
+		}
+		var aa []*Activity
+		for _, login := range reviewers {
+			if login == "" || isBot(login) {
+				continue
+			}
+			aa = append(aa, &Activity{Login: login, Review: true, Files: files})
+		}
+		return aa, nil
+	}, func(i int, aa []*Activity) {
+		acts = append(acts, aa...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acts, nil
+}
+
+// listWritableTeamMembers returns logins of members of each team having write
+// access to the repository by "org/slug".
+func listWritableTeamMembers(ctx context.Context, cli *github.Client, r *github.Repository, pool PoolOptions) (map[string][]string, error) {
+	org := r.GetOwner().GetLogin()
+	tt, err := ListWritableTeams(ctx, cli, r)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make(map[string][]string, len(tt))
+	err = runPool(ctx, len(tt), pool, func(ctx context.Context, i int) ([]string, error) {
+		uu, err := ListTeamMembers(ctx, cli, org, tt[i].GetSlug())
+		if err != nil {
+			return nil, errors.Wrap(err, tt[i].GetSlug())
+		}
+		logins := make([]string, len(uu))
+		for j, u := range uu {
+			logins[j] = u.GetLogin()
+		}
+		return logins, nil
+	}, func(i int, logins []string) {
+		teams[org+"/"+tt[i].GetSlug()] = logins
+	})
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func isBot(login string) bool {
+	return strings.HasSuffix(login, "[bot]")
+}
+
+// RankCandidates credits each activity to the effective rule of files it
+// touched, once per rule, and ranks at most top candidates per rule. Team is
+// credited with activities of its members. Files no rule matches are credited
+// to unowned rules of their top-level directories, which follow the rules of
+// the file in order of pattern. Directories no longer having any of files are
+// not suggested.
+func RankCandidates(f *File, acts []*Activity, files []string, teams map[string][]string, top int) ([]*Suggestion, error) {
+	m, err := NewMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]struct{})
+	for _, p := range files {
+		existing[unownedPattern(p)] = struct{}{}
+	}
+
+	teamsOf := make(map[string][]string)
+	for team, members := range teams {
+		for _, u := range members {
+			teamsOf[strings.ToLower(u)] = append(teamsOf[strings.ToLower(u)], team)
+		}
+	}
+
+	total := make(map[*Rule]int)
+	credits := make(map[*Rule]map[string]*Candidate)
+	credit := func(r *Rule, name string, kind OwnerKind, review bool) {
+		if credits[r] == nil {
+			credits[r] = make(map[string]*Candidate)
+		}
+		c, ok := credits[r][strings.ToLower(name)]
+		if !ok {
+			c = &Candidate{Name: name, Kind: kind}
+			credits[r][strings.ToLower(name)] = c
+		}
+		if review {
+			c.Reviews++
+		} else {
+			c.Commits++
+		}
+	}
+	unowned := make(map[string]*Rule)
+	for _, a := range acts {
+		touched := make(map[*Rule]struct{})
+		for _, p := range a.Files {
+			r := m.Match(p)
+			if r == nil {
+				pattern := unownedPattern(p)
+				if _, ok := existing[pattern]; !ok {
+					continue
+				}
+				if unowned[pattern] == nil {
+					unowned[pattern] = &Rule{Pattern: pattern}
+				}
+				r = unowned[pattern]
+			}
+			touched[r] = struct{}{}
+		}
+		for r := range touched {
+			total[r]++
+			credit(r, a.Login, UserOwner, a.Review)
+			for _, team := range set(teamsOf[strings.ToLower(a.Login)]) {
+				credit(r, team, TeamOwner, a.Review)
+			}
+		}
+	}
+
+	rules := f.Rules()
+	patterns := make([]string, 0, len(unowned))
+	for p := range unowned {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	for _, p := range patterns {
+		rules = append(rules, unowned[p])
+	}
+	ss := make([]*Suggestion, len(rules))
+	for i, r := range rules {
+		cc := make([]*Candidate, 0, len(credits[r]))
+		for _, c := range credits[r] {
+			c.Share = float64(c.Commits+c.Reviews) / float64(total[r])
+			if c.Kind == TeamOwner {
+				c.Share *= activeRatio(teams[c.Name], credits[r])
+			}
+			cc = append(cc, c)
+		}
+		sort.Slice(cc, func(i, j int) bool {
+			if cc[i].Share != cc[j].Share {
+				return cc[i].Share > cc[j].Share
+			}
+			return cc[i].Name < cc[j].Name
+		})
+		if top > 0 && len(cc) > top {
+			cc = cc[:top]
+		}
+		ss[i] = &Suggestion{Rule: r, Candidates: cc, Unowned: r.Line == 0}
+	}
+	return ss, nil
+}
+
+// activeRatio returns the ratio of members credited among every member.
+func activeRatio(members []string, credits map[string]*Candidate) float64 {
+	members = set(members)
+	if len(members) == 0 {
+		return 0
+	}
+	active := 0
+	for _, u := range members {
+		if _, ok := credits[strings.ToLower(u)]; ok {
+			active++
+		}
+	}
+	return float64(active) / float64(len(members))
+}
+
+// unownedPattern returns the pattern of the top-level directory of the path,
+// or the path itself if it's in the root.
+func unownedPattern(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	dir, _, nested := strings.Cut(path, "/")
+	var b strings.Builder
+	b.WriteString("/")
+	for i := 0; i < len(dir); i++ {
+		if strings.IndexByte(" \t#*?[\\", dir[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(dir[i])
+	}
+	if nested {
+		b.WriteString("/")
+	}
+	return b.String()
+}
+
+// ProposeOwners sets the top candidate having write access as the owner of
+// every orphan rule. Unowned rules having such candidate are inserted before
+// the first rule, so that they never override existing ones. It returns the
+// number of rules changed or inserted.
+func (f *File) ProposeOwners(ss []*Suggestion) int {
+	proposed := make(map[*Rule]string)
+	var inserted []*Line
+	for _, s := range ss {
+		if !s.Orphan && !s.Unowned {
+			continue
+		}
+		name := proposedOwner(s.Candidates)
+		if name == "" {
+			continue
+		}
+		if s.Unowned {
+			inserted = append(inserted, &Line{
+				Rule: &Rule{Pattern: s.Rule.Pattern, Space: " ", Owners: []*Owner{newOwner(name, "")}},
+				EOL:  sep,
+			})
+			continue
+		}
+		proposed[s.Rule] = name
+	}
+
+	n := len(inserted)
+	for _, l := range f.Lines {
+		name, ok := proposed[l.Rule]
+		if l.Rule == nil || !ok {
+			continue
+		}
+		if len(l.Rule.Owners) == 0 {
+			l.Rule.Space = " "
+		}
+		l.Rule.Owners = []*Owner{newOwner(name, "")}
+		l.trimTrailingSpace()
+		n++
+	}
+	if len(inserted) > 0 {
+		f.insertBeforeRules(inserted)
+	}
+	return n
+}
+
+// proposedOwner returns the first candidate having write access.
+func proposedOwner(cc []*Candidate) string {
+	for _, c := range cc {
+		if !c.NoWriteAccess {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// insertBeforeRules inserts lines before the first rule, or at the end if
+// there is none.
+func (f *File) insertBeforeRules(lines []*Line) {
+	i := 0
+	for i < len(f.Lines) && f.Lines[i].Rule == nil {
+		i++
+	}
+	if i == len(f.Lines) && i > 0 && f.Lines[i-1].EOL == "" {
+		f.Lines[i-1].EOL = sep
+	}
+	all := make([]*Line, 0, len(f.Lines)+len(lines))
+	all = append(all, f.Lines[:i]...)
+	all = append(all, lines...)
+	f.Lines = append(all, f.Lines[i:]...)
+}
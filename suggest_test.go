@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankCandidates(t *testing.T) {
+	f := Parse("* @a\n/docs/ @gone\n/vendor/\n")
+	acts := []*Activity{
+		{Login: "a", Files: []string{"main.go", "docs/a.md"}},
+		{Login: "b", Files: []string{"docs/a.md", "docs/b.md"}},
+		{Login: "b", Review: true, Files: []string{"docs/c.md"}},
+		{Login: "c", Review: true, Files: []string{"main.go"}},
+	}
+	teams := map[string][]string{
+		"org/docs": {"B", "c"},
+	}
+
+	got, err := RankCandidates(f, acts, nil, teams, 2)
+
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, 1, got[0].Rule.Line)
+	assert.Equal(t, []*Candidate{
+		{Name: "a", Kind: UserOwner, Commits: 1, Share: 0.5},
+		{Name: "c", Kind: UserOwner, Reviews: 1, Share: 0.5},
+	}, got[0].Candidates)
+	assert.Equal(t, []*Candidate{
+		{Name: "b", Kind: UserOwner, Commits: 1, Reviews: 1, Share: 2.0 / 3},
+		{Name: "a", Kind: UserOwner, Commits: 1, Share: 1.0 / 3},
+	}, got[1].Candidates)
+	assert.Empty(t, got[2].Candidates)
+
+	t.Run("teams by active members", func(t *testing.T) {
+		f := Parse("/api/ @gone\n/web/ @gone\n")
+		acts := []*Activity{
+			{Login: "a", Files: []string{"api/a.go", "web/a.js"}},
+			{Login: "b", Files: []string{"api/b.go"}},
+			{Login: "a", Files: []string{"web/b.js"}},
+		}
+		teams := map[string][]string{
+			"org/dev": {"a", "b"},
+		}
+
+		got, err := RankCandidates(f, acts, nil, teams, 0)
+
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		// every member is active
+		assert.Equal(t, []*Candidate{
+			{Name: "org/dev", Kind: TeamOwner, Commits: 2, Share: 1},
+			{Name: "a", Kind: UserOwner, Commits: 1, Share: 0.5},
+			{Name: "b", Kind: UserOwner, Commits: 1, Share: 0.5},
+		}, got[0].Candidates)
+		// only a is active
+		assert.Equal(t, []*Candidate{
+			{Name: "a", Kind: UserOwner, Commits: 2, Share: 1},
+			{Name: "org/dev", Kind: TeamOwner, Commits: 2, Share: 0.5},
+		}, got[1].Candidates)
+	})
+
+	t.Run("unowned files", func(t *testing.T) {
+		f := Parse("/docs/ @a\n")
+		acts := []*Activity{
+			{Login: "a", Files: []string{"docs/a.md", "src/a.go", "src/b/c.go"}},
+			{Login: "b", Files: []string{"Makefile", "src/a.go", "old/a.go"}},
+		}
+		// old/ is deleted
+		files := []string{"Makefile", "docs/a.md", "src/b/c.go"}
+
+		got, err := RankCandidates(f, acts, files, nil, 0)
+
+		require.NoError(t, err)
+		require.Len(t, got, 3)
+		assert.False(t, got[0].Unowned)
+		assert.Equal(t, &Suggestion{
+			Rule:       &Rule{Pattern: "/Makefile"},
+			Candidates: []*Candidate{{Name: "b", Kind: UserOwner, Commits: 1, Share: 1}},
+			Unowned:    true,
+		}, got[1])
+		assert.Equal(t, &Suggestion{
+			Rule: &Rule{Pattern: "/src/"},
+			Candidates: []*Candidate{
+				{Name: "a", Kind: UserOwner, Commits: 1, Share: 0.5},
+				{Name: "b", Kind: UserOwner, Commits: 1, Share: 0.5},
+			},
+			Unowned: true,
+		}, got[2])
+	})
+}
+
+func Test_unownedPattern(t *testing.T) {
+	assert.Equal(t, "/src/", unownedPattern("src/a/b.go"))
+	assert.Equal(t, "/Makefile", unownedPattern("/Makefile"))
+	assert.Equal(t, `/my\ docs/`, unownedPattern("my docs/a.md"))
+}
+
+func Test_isOrphan(t *testing.T) {
+	known := []string{"a", "org/team"}
+
+	assert.False(t, isOrphan(Parse("* @A").Rules()[0], known))
+	assert.False(t, isOrphan(Parse("* @gone @org/team").Rules()[0], known))
+	assert.False(t, isOrphan(Parse("* a@example.com").Rules()[0], known))
+	assert.False(t, isOrphan(Parse("* @gone").Rules()[0], nil))
+	assert.True(t, isOrphan(Parse("* @gone").Rules()[0], known))
+	assert.True(t, isOrphan(Parse("*").Rules()[0], known))
+}
+
+func TestFile_ProposeOwners(t *testing.T) {
+	f := Parse("* @a\n/docs/ @gone # docs\n/vendor/\n/tmp/\n")
+	rules := f.Rules()
+	ss := []*Suggestion{
+		{Rule: rules[0], Candidates: []*Candidate{{Name: "b"}}},
+		{Rule: rules[1], Orphan: true, Candidates: []*Candidate{{Name: "org/docs"}, {Name: "c"}}},
+		{Rule: rules[2], Orphan: true, Candidates: []*Candidate{{Name: "d"}}},
+		{Rule: rules[3], Orphan: true},
+	}
+
+	n := f.ProposeOwners(ss)
+
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "* @a\n/docs/ @org/docs # docs\n/vendor/ @d\n/tmp/\n", f.String())
+
+	t.Run("unowned and no write access", func(t *testing.T) {
+		f := Parse("# owners\n/docs/ @gone\n/vendor/")
+		rules := f.Rules()
+		ss := []*Suggestion{
+			{Rule: rules[0], Orphan: true, Candidates: []*Candidate{{Name: "a", NoWriteAccess: true}, {Name: "b"}}},
+			{Rule: rules[1], Orphan: true, Candidates: []*Candidate{{Name: "c", NoWriteAccess: true}}},
+			{Rule: &Rule{Pattern: "/src/"}, Unowned: true, Orphan: true, Candidates: []*Candidate{{Name: "org/dev"}}},
+			{Rule: &Rule{Pattern: "/tmp/"}, Unowned: true, Orphan: true, Candidates: []*Candidate{{Name: "d", NoWriteAccess: true}}},
+		}
+
+		n := f.ProposeOwners(ss)
+
+		assert.Equal(t, 2, n)
+		assert.Equal(t, "# owners\n/src/ @org/dev\n/docs/ @b\n/vendor/", f.String())
+	})
+
+	t.Run("no rule", func(t *testing.T) {
+		f := Parse("# owners")
+
+		n := f.ProposeOwners([]*Suggestion{{Rule: &Rule{Pattern: "/src/"}, Unowned: true, Candidates: []*Candidate{{Name: "a"}}}})
+
+		assert.Equal(t, 1, n)
+		assert.Equal(t, "# owners\n/src/ @a\n", f.String())
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// TeamTree is hierarchy of teams in an organization. Slugs are compared case
+// insensitively.
+type TeamTree struct {
+	org      string
+	slugs    map[string]string
+	parents  map[string]string
+	children map[string][]string
+}
+
+// NewTeamTree builds hierarchy of teams by their parents.
+func NewTeamTree(org string, teams []*github.Team) *TeamTree {
+	t := &TeamTree{
+		org:      org,
+		slugs:    make(map[string]string, len(teams)),
+		parents:  make(map[string]string),
+		children: make(map[string][]string),
+	}
+	for _, team := range teams {
+		t.slugs[strings.ToLower(team.GetSlug())] = team.GetSlug()
+	}
+	for _, team := range teams {
+		p := team.GetParent().GetSlug()
+		if p == "" {
+			continue
+		}
+		t.parents[strings.ToLower(team.GetSlug())] = p
+		t.children[strings.ToLower(p)] = append(t.children[strings.ToLower(p)], team.GetSlug())
+	}
+	return t
+}
+
+// loadTeamTree returns hierarchy of every team in the organization.
+func loadTeamTree(ctx context.Context, cli *github.Client, org string) (*TeamTree, error) {
+	teams, err := ListTeams(ctx, cli, org)
+	if err != nil {
+		return nil, err
+	}
+	return NewTeamTree(org, teams), nil
+}
+
+// Names returns every team as "org/slug" in order of slug.
+func (t *TeamTree) Names() []string {
+	names := make([]string, 0, len(t.slugs))
+	for _, s := range t.slugs {
+		names = append(names, t.org+"/"+s)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Exists reports whether the team exists.
+func (t *TeamTree) Exists(slug string) bool {
+	_, ok := t.slugs[strings.ToLower(slug)]
+	return ok
+}
+
+// Parent returns slug of the parent team, or empty string if the team is at
+// the top.
+func (t *TeamTree) Parent(slug string) string {
+	return t.parents[strings.ToLower(slug)]
+}
+
+// Children returns slugs of teams nested directly in the team.
+func (t *TeamTree) Children(slug string) []string {
+	return t.children[strings.ToLower(slug)]
+}
+
+// Ancestors returns slugs of the parent team, its parent and so on.
+func (t *TeamTree) Ancestors(slug string) []string {
+	var aa []string
+	seen := map[string]struct{}{strings.ToLower(slug): {}}
+	for p := t.Parent(slug); p != ""; p = t.Parent(p) {
+		if _, ok := seen[strings.ToLower(p)]; ok {
+			break
+		}
+		seen[strings.ToLower(p)] = struct{}{}
+		aa = append(aa, p)
+	}
+	return aa
+}
+
+// GuessParent returns the existing team which the deleted team was likely
+// nested in. Since deleted team is unknown to GitHub, it's guessed by the
+// longest slug prefixing the deleted one followed by "-" or "_", e.g.
+// "backend" for "backend-payments". It returns empty string if nothing is
+// found.
+func (t *TeamTree) GuessParent(slug string) string {
+	s := strings.ToLower(slug)
+	for {
+		i := strings.LastIndexAny(s, "-_")
+		if i <= 0 {
+			return ""
+		}
+		s = s[:i]
+		if p, ok := t.slugs[s]; ok {
+			return p
+		}
+	}
+}
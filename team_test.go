@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamTree(t *testing.T) {
+	newTeam := func(slug, parent string) *github.Team {
+		team := &github.Team{Slug: github.String(slug)}
+		if parent != "" {
+			team.Parent = &github.Team{Slug: github.String(parent)}
+		}
+		return team
+	}
+	tree := NewTeamTree("org", []*github.Team{
+		newTeam("backend", ""),
+		newTeam("backend-api", "backend"),
+		newTeam("backend-api-v2", "backend-api"),
+		newTeam("frontend", ""),
+	})
+
+	assert.Equal(t, []string{"org/backend", "org/backend-api", "org/backend-api-v2", "org/frontend"}, tree.Names())
+	assert.True(t, tree.Exists("Backend-API"))
+	assert.False(t, tree.Exists("backend-payments"))
+	assert.Equal(t, "backend", tree.Parent("backend-api"))
+	assert.Equal(t, "", tree.Parent("backend"))
+	assert.Equal(t, []string{"backend-api"}, tree.Children("backend"))
+	assert.Equal(t, []string{"backend-api", "backend"}, tree.Ancestors("backend-api-v2"))
+}
+
+func TestTeamTree_GuessParent(t *testing.T) {
+	tree := NewTeamTree("org", []*github.Team{
+		{Slug: github.String("backend")},
+		{Slug: github.String("backend-api")},
+	})
+
+	cases := []struct {
+		name     string
+		given    string
+		expected string
+	}{
+		{name: "child", given: "backend-payments", expected: "backend"},
+		{name: "longest prefix", given: "backend-api-v1", expected: "backend-api"},
+		{name: "underscore", given: "Backend_ops", expected: "backend"},
+		{name: "no parent", given: "frontend-web", expected: ""},
+		{name: "no separator", given: "backends", expected: ""},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tree.GuessParent(tc.given))
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// default templates of replace, which are rendered with ChangeData
+const (
+	defaultCommitMessageTemplate = `{{if eq (len .Replacements) 1}}{{index .Replacements 0}}{{else if .Replacements}}Update codeowners
+
+{{range .Replacements}}- {{.}}
+{{end}}{{end}}`
+	defaultPRTitleTemplate = `{{with .CommitMessage}}{{firstLine .}}{{else}}Remove ignored codeowners{{end}}`
+	defaultPRBodyTemplate  = "Update codeowners.\n{{.Changes}}"
+)
+
+// ChangeData is a change of a repository made by replace, which templates of
+// commit message, pull request title and body are rendered with.
+type ChangeData struct {
+	// Repo is the full name as "owner/name".
+	Repo  string
+	Owner string
+	Name  string
+	// Path is of the effective CODEOWNERS file and FileURL is the link to it
+	// in the pull request branch.
+	Path    string
+	FileURL string
+	Branch  string
+	// Replacements are applied ones. OldOwners and NewOwners are their owners
+	// without duplicates, and removed owners have no new one.
+	Replacements []Replacement
+	OldOwners    []string
+	NewOwners    []string
+	// Deleted are paths of CODEOWNERS files ignored by GitHub to be deleted.
+	Deleted []string
+	// Diff is the unified diff of every file and Lines is the number of lines
+	// changed in Path.
+	Diff  string
+	Lines int
+	RunID string
+	// Changes is the markdown list of replacements and deleted files.
+	Changes string
+	// CommitMessage is the rendered commit message. It's empty in the
+	// template of commit message, or if only shadowed files are deleted.
+	CommitMessage string
+}
+
+// newChangeData fills fields derived from the others.
+func newChangeData(d ChangeData) *ChangeData {
+	for _, r := range d.Replacements {
+		if !contains(d.OldOwners, r.Old) {
+			d.OldOwners = append(d.OldOwners, r.Old)
+		}
+		if r.New != "" && !contains(d.NewOwners, r.New) {
+			d.NewOwners = append(d.NewOwners, r.New)
+		}
+	}
+	var b strings.Builder
+	if len(d.Replacements) > 0 {
+		fmt.Fprintf(&b, "%sChanges in [%s](%s):%s%s", sep, d.Path, d.FileURL, sep, sep)
+		for _, r := range d.Replacements {
+			fmt.Fprintf(&b, "- %s%s", r, sep)
+		}
+	}
+	for _, p := range d.Deleted {
+		fmt.Fprintf(&b, "%sRemoved `%s` ignored by [%s](%s).%s", sep, p, d.Path, d.FileURL, sep)
+	}
+	d.Changes = b.String()
+	return &d
+}
+
+// sampleChangeData and sampleDeletionData are rendered to validate
+// templates. The commit message is rendered only with replacements, and the
+// others are also rendered when only shadowed files are deleted.
+var sampleChangeData = newChangeData(ChangeData{
+	Repo: "owner/repo", Owner: "owner", Name: "repo",
+	Path: "CODEOWNERS", FileURL: "https://github.com/owner/repo/blob/update-codeowners/CODEOWNERS", Branch: "update-codeowners",
+	Replacements:  []Replacement{{Old: "old", New: "new"}},
+	Diff:          "--- a/CODEOWNERS\n+++ b/CODEOWNERS\n@@ -1 +1 @@\n-* @old\n+* @new\n",
+	Lines:         1,
+	RunID:         "1",
+	CommitMessage: "Update old to new",
+})
+
+var sampleDeletionData = newChangeData(ChangeData{
+	Repo: "owner/repo", Owner: "owner", Name: "repo",
+	Path: ".github/CODEOWNERS", FileURL: "https://github.com/owner/repo/blob/update-codeowners/.github/CODEOWNERS", Branch: "update-codeowners",
+	Deleted: []string{"CODEOWNERS"},
+	Diff:    "--- a/CODEOWNERS\n+++ b/CODEOWNERS\n@@ -1 +0,0 @@\n-* @old\n",
+	RunID:   "1",
+})
+
+// ReplaceTemplates are templates of what replace writes for each repository.
+type ReplaceTemplates struct {
+	CommitMessage *template.Template
+	PRTitle       *template.Template
+	PRBody        *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"firstLine": func(s string) string {
+		return strings.SplitN(s, sep, 2)[0]
+	},
+	"mention": func(owner string) string {
+		if strings.Contains(owner, "@") {
+			return owner
+		}
+		return mentionPrefix + owner
+	},
+}
+
+// NewReplaceTemplates parses templates of commit message, pull request title
+// and body. Referring to unknown fields is an error.
+func NewReplaceTemplates(commitMessage, prTitle, prBody string) (*ReplaceTemplates, error) {
+	parse := func(name, text string, samples ...*ChangeData) (*template.Template, error) {
+		t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid template of %s", name)
+		}
+		// catch unknown fields, functions and out of range indexes before
+		// any change
+		for _, d := range samples {
+			if err := t.Execute(&strings.Builder{}, d); err != nil {
+				return nil, errors.Wrapf(err, "invalid template of %s", name)
+			}
+		}
+		return t, nil
+	}
+	var (
+		t   ReplaceTemplates
+		err error
+	)
+	if t.CommitMessage, err = parse("commit message", commitMessage, sampleChangeData); err != nil {
+		return nil, err
+	}
+	if t.PRTitle, err = parse("pull request title", prTitle, sampleChangeData, sampleDeletionData); err != nil {
+		return nil, err
+	}
+	if t.PRBody, err = parse("pull request body", prBody, sampleChangeData, sampleDeletionData); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Render renders the commit message, and then the pull request title and body
+// with it. The title is trimmed to a line.
+func (t *ReplaceTemplates) Render(d *ChangeData) (msg, title, body string, err error) {
+	execute := func(tmpl *template.Template, d *ChangeData) (string, error) {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, d); err != nil {
+			return "", errors.Wrap(err, "template.Template.Execute")
+		}
+		return b.String(), nil
+	}
+	if len(d.Replacements) > 0 {
+		if msg, err = execute(t.CommitMessage, d); err != nil {
+			return "", "", "", err
+		}
+		msg = strings.TrimSpace(msg)
+	}
+	withMsg := *d
+	withMsg.CommitMessage = msg
+	if title, err = execute(t.PRTitle, &withMsg); err != nil {
+		return "", "", "", err
+	}
+	title = strings.TrimSpace(strings.SplitN(strings.TrimSpace(title), sep, 2)[0])
+	if title == "" {
+		return "", "", "", errors.New("empty pull request title")
+	}
+	if body, err = execute(t.PRBody, &withMsg); err != nil {
+		return "", "", "", err
+	}
+	return msg, title, body, nil
+}
+
+// countChangedLines counts lines removed or modified by the unified diff.
+func countChangedLines(diff string) int {
+	n := 0
+	for _, l := range strings.Split(diff, sep) {
+		if strings.HasPrefix(l, "-") && !strings.HasPrefix(l, "--- ") {
+			n++
+		}
+	}
+	return n
+}
+
+// defaultRunID identifies a run of replace. It's the ID of GitHub Actions
+// workflow run if any, or the start time.
+func defaultRunID() string {
+	if id := os.Getenv("GITHUB_RUN_ID"); id != "" {
+		return id
+	}
+	return time.Now().UTC().Format("20060102T150405Z")
+}
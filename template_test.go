@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceTemplates_Render(t *testing.T) {
+	data := func(rr []Replacement, deleted []string) *ChangeData {
+		return newChangeData(ChangeData{
+			Repo: "org/repo", Owner: "org", Name: "repo",
+			Path: ".github/CODEOWNERS", FileURL: "https://github.com/org/repo/blob/update-codeowners/.github/CODEOWNERS", Branch: "update-codeowners",
+			Replacements: rr,
+			Deleted:      deleted,
+			Lines:        2,
+			RunID:        "42",
+		})
+	}
+
+	cases := []struct {
+		name          string
+		commitMessage string
+		prTitle       string
+		prBody        *string
+		data          *ChangeData
+		expectedMsg   string
+		expectedTitle string
+		expectedBody  string
+	}{
+		{
+			name:          "default of a replacement",
+			data:          data([]Replacement{{Old: "a", New: "b"}}, nil),
+			expectedMsg:   "Update a to b",
+			expectedTitle: "Update a to b",
+			expectedBody:  "Update codeowners.\n\nChanges in [.github/CODEOWNERS](https://github.com/org/repo/blob/update-codeowners/.github/CODEOWNERS):\n\n- Update a to b\n",
+		},
+		{
+			name:          "default of replacements and deleted",
+			data:          data([]Replacement{{Old: "a", New: "b"}, {Old: "c"}}, []string{"CODEOWNERS"}),
+			expectedMsg:   "Update codeowners\n\n- Update a to b\n- Remove c",
+			expectedTitle: "Update codeowners",
+			expectedBody:  "Update codeowners.\n\nChanges in [.github/CODEOWNERS](https://github.com/org/repo/blob/update-codeowners/.github/CODEOWNERS):\n\n- Update a to b\n- Remove c\n\nRemoved `CODEOWNERS` ignored by [.github/CODEOWNERS](https://github.com/org/repo/blob/update-codeowners/.github/CODEOWNERS).\n",
+		},
+		{
+			name:          "default of only deleted",
+			data:          data(nil, []string{"CODEOWNERS"}),
+			expectedMsg:   "",
+			expectedTitle: "Remove ignored codeowners",
+			expectedBody:  "Update codeowners.\n\nRemoved `CODEOWNERS` ignored by [.github/CODEOWNERS](https://github.com/org/repo/blob/update-codeowners/.github/CODEOWNERS).\n",
+		},
+		{
+			name:          "custom",
+			commitMessage: "chore({{.Name}}): replace {{join .OldOwners \", \"}}",
+			prTitle:       "[{{.RunID}}] {{.CommitMessage}}",
+			prBody:        github.String("{{range .OldOwners}}{{mention .}} {{end}}in {{.Lines}} lines of {{.Repo}}"),
+			data:          data([]Replacement{{Old: "a", New: "b"}, {Old: "org/c"}, {Old: "a", New: "d"}}, nil),
+			expectedMsg:   "chore(repo): replace a, org/c",
+			expectedTitle: "[42] chore(repo): replace a, org/c",
+			expectedBody:  "@a @org/c in 2 lines of org/repo",
+		},
+		{
+			name:          "empty body",
+			prBody:        github.String(""),
+			data:          data([]Replacement{{Old: "a", New: "b"}}, nil),
+			expectedMsg:   "Update a to b",
+			expectedTitle: "Update a to b",
+			expectedBody:  "",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := newReplaceTemplates(tc.commitMessage, tc.prTitle, tc.prBody)
+			require.NoError(t, err)
+
+			msg, title, body, err := tmpl.Render(tc.data)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedMsg, msg)
+			assert.Equal(t, tc.expectedTitle, title)
+			assert.Equal(t, tc.expectedBody, body)
+		})
+	}
+
+	t.Run("empty title", func(t *testing.T) {
+		tmpl, err := newReplaceTemplates("", "{{if false}}title{{end}}", nil)
+		require.NoError(t, err)
+
+		_, _, _, err = tmpl.Render(data([]Replacement{{Old: "a"}}, nil))
+
+		assert.EqualError(t, err, "empty pull request title")
+	})
+}
+
+func TestNewReplaceTemplates(t *testing.T) {
+	cases := []struct {
+		name          string
+		commitMessage string
+		prTitle       string
+		prBody        *string
+		expected      string
+	}{
+		{name: "syntax", commitMessage: "{{.Repo", expected: "invalid template of commit message"},
+		{name: "unknown field", prTitle: "{{.Title}}", expected: "invalid template of pull request title"},
+		{name: "unknown function", prBody: github.String("{{upper .Repo}}"), expected: "invalid template of pull request body"},
+		{name: "no replacement", prTitle: "{{index .Replacements 0}}", expected: "invalid template of pull request title"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newReplaceTemplates(tc.commitMessage, tc.prTitle, tc.prBody)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestNewReplaceTemplates_commitMessage(t *testing.T) {
+	// commit message is rendered only with replacements
+	_, err := newReplaceTemplates("{{index .Replacements 0}}", "", nil)
+
+	assert.NoError(t, err)
+}
+
+func Test_countChangedLines(t *testing.T) {
+	diff := UnifiedDiff("CODEOWNERS", "* @a\n/b/ @b\n/c/ @a @c\n", "* @z\n/b/ @b\n/c/ @c\n")
+
+	assert.Equal(t, 2, countChangedLines(diff))
+}
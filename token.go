@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNoToken = errors.New("no token found")
+)
+
+// FindToken looks up GitHub token of host, e.g. "github.com" or
+// "github.example.com", in order of environment variables, gh CLI config and
+// netrc. It returns ErrNoToken describing where it looked if nothing is found.
+func FindToken(host string) (string, error) {
+	return findToken(host, os.Getenv)
+}
+
+func findToken(host string, getenv func(string) string) (string, error) {
+	host = strings.ToLower(host)
+	looked := make([]string, 0)
+
+	for _, k := range tokenEnvs(host, getenv) {
+		if v := getenv(k); v != "" {
+			return v, nil
+		}
+		looked = append(looked, "$"+k)
+	}
+
+	if path := ghHostsPath(getenv); path != "" {
+		token, err := tokenFromGHHosts(path, host)
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+		looked = append(looked, path)
+	}
+
+	if path := netrcPath(getenv); path != "" {
+		token, err := tokenFromNetrc(path, host)
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+		looked = append(looked, path)
+	}
+
+	err := errors.Wrapf(ErrNoToken, "looked in %s for %s", strings.Join(looked, ", "), host)
+	if ignored := ignoredTokenEnvs(host, getenv); len(ignored) > 0 {
+		err = errors.Wrapf(err, "%s ignored since $GITHUB_SERVER_URL is not of %s", strings.Join(ignored, ", "), host)
+	}
+	return "", err
+}
+
+var dotComTokenEnvs = []string{"GH_TOKEN", "GITHUB_TOKEN"}
+
+// tokenEnvs returns environment variables holding token of host following gh
+// CLI convention. Those of github.com are also used for GitHub Enterprise
+// Server which GitHub Actions runs on, where GITHUB_TOKEN is of the host.
+func tokenEnvs(host string, getenv func(string) string) []string {
+	if host == defaultHost {
+		return dotComTokenEnvs
+	}
+	envs := []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	if isActionsHost(host, getenv) {
+		envs = append(envs, dotComTokenEnvs...)
+	}
+	return envs
+}
+
+// ignoredTokenEnvs returns environment variables which are set but not used
+// for host.
+func ignoredTokenEnvs(host string, getenv func(string) string) []string {
+	if host == defaultHost || isActionsHost(host, getenv) {
+		return nil
+	}
+	var ignored []string
+	for _, k := range dotComTokenEnvs {
+		if getenv(k) != "" {
+			ignored = append(ignored, "$"+k)
+		}
+	}
+	return ignored
+}
+
+// isActionsHost reports whether GitHub Actions runs on host.
+func isActionsHost(host string, getenv func(string) string) bool {
+	for _, k := range []string{"GITHUB_SERVER_URL", "GITHUB_API_URL"} {
+		u, err := url.Parse(getenv(k))
+		if err == nil && u.Hostname() != "" && strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+func homeDir(getenv func(string) string) string {
+	if home := getenv("HOME"); home != "" {
+		return home
+	}
+	return getenv("USERPROFILE")
+}
+
+func ghHostsPath(getenv func(string) string) string {
+	if dir := getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if home := homeDir(getenv); home != "" {
+		return filepath.Join(home, ".config", "gh", "hosts.yml")
+	}
+	return ""
+}
+
+func netrcPath(getenv func(string) string) string {
+	if path := getenv("NETRC"); path != "" {
+		return path
+	}
+	if home := homeDir(getenv); home != "" {
+		return filepath.Join(home, ".netrc")
+	}
+	return ""
+}
+
+// tokenFromGHHosts returns oauth_token of host in hosts.yml of gh CLI. It
+// returns empty string if the file or the host doesn't exist.
+func tokenFromGHHosts(path, host string) (string, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "os.ReadFile")
+	}
+
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(b, &hosts); err != nil {
+		return "", errors.Wrapf(err, "yaml.Unmarshal %s", path)
+	}
+	for h, v := range hosts {
+		if strings.EqualFold(h, host) {
+			return v.OAuthToken, nil
+		}
+	}
+	return "", nil
+}
+
+// tokenFromNetrc returns password of the machine which is either host or its
+// API host in netrc. It returns empty string if the file or the machine
+// doesn't exist.
+func tokenFromNetrc(path, host string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "os.Open")
+	}
+	defer f.Close()
+
+	var (
+		fields  []string
+		inMacro bool
+	)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		// macro definition continues until a blank line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if field == "macdef" {
+				inMacro = true
+				break
+			}
+			fields = append(fields, field)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", errors.Wrap(err, "bufio.Scanner.Scan")
+	}
+
+	machines := map[string]struct{}{
+		host:          {},
+		"api." + host: {},
+	}
+	matched := false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			i++
+			if i < len(fields) {
+				_, matched = machines[strings.ToLower(fields[i])]
+			}
+		case "default":
+			matched = true
+		case "login", "account":
+			i++
+		case "password":
+			i++
+			if matched && i < len(fields) {
+				return fields[i], nil
+			}
+		}
+	}
+	return "", nil
+}
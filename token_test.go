@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_findToken(t *testing.T) {
+	const hosts = `github.com:
+    user: someone
+    oauth_token: gh-dotcom-token
+github.example.com:
+    oauth_token: gh-enterprise-token
+`
+	const netrc = `machine other.example.com login someone password other-token
+macdef init
+machine github.example.com password macro-token
+
+machine api.github.com
+    login someone
+    password netrc-dotcom-token
+default login anonymous password default-token
+`
+
+	cases := []struct {
+		name        string
+		host        string
+		env         map[string]string
+		hosts       string
+		netrc       string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "GH_TOKEN first",
+			host:     "github.com",
+			env:      map[string]string{"GH_TOKEN": "gh-token", "GITHUB_TOKEN": "github-token"},
+			hosts:    hosts,
+			expected: "gh-token",
+		},
+		{
+			name:     "GITHUB_TOKEN",
+			host:     "github.com",
+			env:      map[string]string{"GITHUB_TOKEN": "github-token"},
+			hosts:    hosts,
+			expected: "github-token",
+		},
+		{
+			name:     "enterprise token from env",
+			host:     "github.example.com",
+			env:      map[string]string{"GITHUB_TOKEN": "github-token", "GH_ENTERPRISE_TOKEN": "enterprise-token"},
+			expected: "enterprise-token",
+		},
+		{
+			name:     "GITHUB_TOKEN of GitHub Actions on enterprise",
+			host:     "github.example.com",
+			env:      map[string]string{"GITHUB_TOKEN": "github-token", "GITHUB_SERVER_URL": "https://github.example.com", "GITHUB_API_URL": "https://github.example.com/api/v3"},
+			hosts:    hosts,
+			expected: "github-token",
+		},
+		{
+			name:     "gh hosts",
+			host:     "github.com",
+			hosts:    hosts,
+			netrc:    netrc,
+			expected: "gh-dotcom-token",
+		},
+		{
+			name:     "gh hosts for enterprise",
+			host:     "GitHub.example.com",
+			hosts:    hosts,
+			expected: "gh-enterprise-token",
+		},
+		{
+			name:     "netrc with api host",
+			host:     "github.com",
+			netrc:    netrc,
+			expected: "netrc-dotcom-token",
+		},
+		{
+			name:     "netrc default ignoring macro",
+			host:     "github.example.com",
+			netrc:    netrc,
+			expected: "default-token",
+		},
+		{
+			name:        "not found",
+			host:        "github.com",
+			expectedErr: "looked in $GH_TOKEN, $GITHUB_TOKEN, HOME/.config/gh/hosts.yml, HOME/.netrc for github.com",
+		},
+		{
+			name:        "GITHUB_TOKEN of another host",
+			host:        "github.example.com",
+			env:         map[string]string{"GITHUB_TOKEN": "github-token", "GITHUB_SERVER_URL": "https://github.com"},
+			expectedErr: "$GITHUB_TOKEN ignored since $GITHUB_SERVER_URL is not of github.example.com: looked in $GH_ENTERPRISE_TOKEN, $GITHUB_ENTERPRISE_TOKEN, HOME/.config/gh/hosts.yml, HOME/.netrc for github.example.com",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			if tc.hosts != "" {
+				require.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "gh"), 0o700))
+				require.NoError(t, os.WriteFile(filepath.Join(home, ".config", "gh", "hosts.yml"), []byte(tc.hosts), 0o600))
+			}
+			if tc.netrc != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(home, ".netrc"), []byte(tc.netrc), 0o600))
+			}
+			getenv := func(k string) string {
+				if k == "HOME" {
+					return home
+				}
+				return tc.env[k]
+			}
+
+			got, err := findToken(tc.host, getenv)
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, ErrNoToken, errors.Cause(err))
+				assert.Contains(t, err.Error(), strings.ReplaceAll(tc.expectedErr, "HOME/", home+string(filepath.Separator)))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}